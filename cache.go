@@ -0,0 +1,159 @@
+package gdrive
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCacheTTL is how long a cached fileId will be trusted. Contents could be changed by
+	// others, so it should not be too long.
+	defaultCacheTTL = time.Minute
+	// defaultCacheSize is the max count of cached paths.
+	defaultCacheSize = 100000
+)
+
+// Cache caches `path -> fileId` as described in RFC-14, so that we don't need to walk the whole
+// path every time.
+//
+// Paths are slash separated without leading or trailing slash. Implementations should be safe
+// for concurrent use, and handle their failures by themselves, e.g. treat them as cache misses,
+// as the cache is only an optimization.
+type Cache interface {
+	// Get returns the fileId of the path.
+	Get(path string) (fileId string, found bool)
+	// Set sets the fileId of the path.
+	Set(path string, fileId string)
+	// Delete deletes the path.
+	Delete(path string)
+	// DeleteTree deletes the path and all of it's descendants, which start with `path + "/"`.
+	DeleteTree(path string)
+}
+
+// NewLRUCache will create an in-memory Cache which holds size paths at most, the least recently
+// used path will be evicted first. Every path expires after ttl, and will never expire if ttl is 0.
+func NewLRUCache(size int, ttl time.Duration) Cache {
+	return newLRUCache(size, ttl)
+}
+
+type lruCache struct {
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	path   string
+	fileId string
+	// expire is zero if the path will never expire.
+	expire time.Time
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) Get(path string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[path]
+	if !ok {
+		return "", false
+	}
+	e := elem.Value.(*lruEntry)
+	if !e.expire.IsZero() && time.Now().After(e.expire) {
+		c.removeElement(elem)
+		return "", false
+	}
+
+	c.ll.MoveToFront(elem)
+	return e.fileId, true
+}
+
+func (c *lruCache) Set(path string, fileId string) {
+	var expire time.Time
+	if c.ttl > 0 {
+		expire = time.Now().Add(c.ttl)
+	}
+	c.set(path, fileId, expire)
+}
+
+// set will set the path with an explicit expire time.
+func (c *lruCache) set(path string, fileId string, expire time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[path]; ok {
+		e := elem.Value.(*lruEntry)
+		e.fileId, e.expire = fileId, expire
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[path] = c.ll.PushFront(&lruEntry{path: path, fileId: fileId, expire: expire})
+	for c.size > 0 && c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *lruCache) Delete(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[path]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruCache) DeleteTree(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := path + "/"
+	for k, elem := range c.items {
+		if k == path || path == "" || strings.HasPrefix(k, prefix) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// entries returns all the entries which are not expired, from the least recently used one.
+func (c *lruCache) entries() []lruEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	es := make([]lruEntry, 0, c.ll.Len())
+	for elem := c.ll.Back(); elem != nil; elem = elem.Prev() {
+		e := elem.Value.(*lruEntry)
+		if !e.expire.IsZero() && now.After(e.expire) {
+			continue
+		}
+		es = append(es, *e)
+	}
+	return es
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).path)
+}
+
+// len returns the count of paths in memory, including the expired ones.
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
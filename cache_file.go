@@ -0,0 +1,234 @@
+package gdrive
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	fileCacheOpSet        = "set"
+	fileCacheOpDelete     = "delete"
+	fileCacheOpDeleteTree = "delete_tree"
+
+	// fileCacheCompactThreshold is the least count of records before the log will be compacted.
+	fileCacheCompactThreshold = 1024
+)
+
+// fileCacheRecord is a line in the log of fileCache.
+type fileCacheRecord struct {
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	FileID string `json:"file_id,omitempty"`
+	// Expire is the unix nano of the expire time, 0 means never expire.
+	Expire int64 `json:"expire,omitempty"`
+}
+
+// FileCache is a Cache persisted in a file, it should be closed once it's no longer used.
+type FileCache interface {
+	Cache
+	io.Closer
+}
+
+// NewFileCache will create a Cache persisted in the file at path, so that it could be reused
+// after the process restarted. Paths are held in memory like NewLRUCache, and every change will
+// be appended into the file, which will be compacted while it grows too large.
+//
+// The file should not be shared by multiple processes at the same time.
+func NewFileCache(path string, size int, ttl time.Duration) (FileCache, error) {
+	return newFileCache(path, size, ttl)
+}
+
+type fileCache struct {
+	*lruCache
+
+	path string
+
+	// mu guards the file, changes in memory are made under it too, so that they are appended
+	// in the same order.
+	mu sync.Mutex
+	f  *os.File
+	// records is the count of records in the file.
+	records int
+}
+
+func newFileCache(path string, size int, ttl time.Duration) (c *fileCache, err error) {
+	c = &fileCache{
+		lruCache: newLRUCache(size, ttl),
+		path:     path,
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	err = c.load()
+	if err != nil {
+		return nil, err
+	}
+	// Drop the outdated records left by the last process.
+	err = c.compact()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load will replay the records in the file.
+func (c *fileCache) load() (err error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r fileCacheRecord
+		// The last record could be broken if the process crashed while writing, skip it.
+		if json.Unmarshal(scanner.Bytes(), &r) != nil {
+			continue
+		}
+
+		switch r.Op {
+		case fileCacheOpSet:
+			var expire time.Time
+			if r.Expire != 0 {
+				expire = time.Unix(0, r.Expire)
+				if now.After(expire) {
+					continue
+				}
+			}
+			c.lruCache.set(r.Path, r.FileID, expire)
+		case fileCacheOpDelete:
+			c.lruCache.Delete(r.Path)
+		case fileCacheOpDeleteTree:
+			c.lruCache.DeleteTree(r.Path)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact will rewrite the file with the paths in memory.
+func (c *fileCache) compact() (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	es := c.lruCache.entries()
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range es {
+		r := fileCacheRecord{Op: fileCacheOpSet, Path: e.path, FileID: e.fileId}
+		if !e.expire.IsZero() {
+			r.Expire = e.expire.UnixNano()
+		}
+		err = enc.Encode(r)
+		if err != nil {
+			return err
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), c.path)
+	if err != nil {
+		return err
+	}
+
+	// The offset of temp file is at the end already, following records will be appended.
+	if c.f != nil {
+		_ = c.f.Close()
+	}
+	c.f = tmp
+	c.records = len(es)
+	return nil
+}
+
+func (c *fileCache) Set(path string, fileId string) {
+	var expire time.Time
+	if c.ttl > 0 {
+		expire = time.Now().Add(c.ttl)
+	}
+	r := fileCacheRecord{Op: fileCacheOpSet, Path: path, FileID: fileId}
+	if !expire.IsZero() {
+		r.Expire = expire.UnixNano()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lruCache.set(path, fileId, expire)
+	c.append(r)
+}
+
+func (c *fileCache) Delete(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lruCache.Delete(path)
+	c.append(fileCacheRecord{Op: fileCacheOpDelete, Path: path})
+}
+
+func (c *fileCache) DeleteTree(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lruCache.DeleteTree(path)
+	c.append(fileCacheRecord{Op: fileCacheOpDeleteTree, Path: path})
+}
+
+// Close will close the file, following changes will only be made in memory.
+func (c *fileCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.f == nil {
+		return nil
+	}
+	err := c.f.Close()
+	c.f = nil
+	return err
+}
+
+// append will append the record into the file, c.mu should be held. Failures are ignored as the
+// content in memory is still correct, and the file will be rewritten by the next compaction.
+func (c *fileCache) append(r fileCacheRecord) {
+	if c.f == nil {
+		return
+	}
+
+	content, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	_, err = c.f.Write(append(content, '\n'))
+	if err != nil {
+		// A partial written record would break the next one, so rewrite the file.
+		_ = c.compact()
+		return
+	}
+
+	c.records++
+	if c.records > fileCacheCompactThreshold && c.records > 2*c.lruCache.len() {
+		_ = c.compact()
+	}
+}
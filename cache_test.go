@@ -0,0 +1,384 @@
+package gdrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestLRUCacheGet(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, time.Minute)
+	c.Set("a/b", "id-b")
+
+	if id, found := c.Get("a/b"); !found || id != "id-b" {
+		t.Errorf("get a/b = %q, %v, expect id-b, true", id, found)
+	}
+	if _, found := c.Get("a"); found {
+		t.Errorf("get a should not be found")
+	}
+}
+
+func TestLRUCacheExpire(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, 50*time.Millisecond)
+	c.Set("a", "id-a")
+
+	if _, found := c.Get("a"); !found {
+		t.Fatalf("get a should be found before expired")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, found := c.Get("a"); found {
+		t.Errorf("get a should not be found after expired")
+	}
+}
+
+func TestLRUCacheNeverExpire(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, 0)
+	c.Set("a", "id-a")
+
+	time.Sleep(50 * time.Millisecond)
+	if _, found := c.Get("a"); !found {
+		t.Errorf("get a should be found while ttl is 0")
+	}
+}
+
+func TestLRUCacheUpdate(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, time.Minute)
+	c.Set("a", "id-old")
+	c.Set("a", "id-new")
+
+	if id, _ := c.Get("a"); id != "id-new" {
+		t.Errorf("get a = %q, expect id-new", id)
+	}
+}
+
+func TestLRUCacheEvict(t *testing.T) {
+	c := NewLRUCache(2, time.Minute)
+	c.Set("a", "id-a")
+	c.Set("b", "id-b")
+	// Make a recently used, so that b will be evicted.
+	c.Get("a")
+	c.Set("c", "id-c")
+
+	if _, found := c.Get("b"); found {
+		t.Errorf("get b should not be found after evicted")
+	}
+	for _, path := range []string{"a", "c"} {
+		if _, found := c.Get(path); !found {
+			t.Errorf("get %q should be found", path)
+		}
+	}
+}
+
+func TestLRUCacheDelete(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, time.Minute)
+	c.Set("a", "id-a")
+	c.Set("a/b", "id-b")
+
+	c.Delete("a")
+
+	if _, found := c.Get("a"); found {
+		t.Errorf("get a should not be found after deleted")
+	}
+	if _, found := c.Get("a/b"); !found {
+		t.Errorf("get a/b should be found after a deleted")
+	}
+}
+
+func TestLRUCacheDeleteTree(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, time.Minute)
+	for _, path := range []string{"a", "a/b", "a/b/c", "a/b/c/d.txt", "a/bc", "a/bc/d.txt", "e"} {
+		c.Set(path, "id-"+path)
+	}
+
+	c.DeleteTree("a/b")
+
+	for _, path := range []string{"a/b", "a/b/c", "a/b/c/d.txt"} {
+		if _, found := c.Get(path); found {
+			t.Errorf("get %q should not be found after a/b deleted", path)
+		}
+	}
+	// Siblings sharing the same prefix must be kept.
+	for _, path := range []string{"a", "a/bc", "a/bc/d.txt", "e"} {
+		if _, found := c.Get(path); !found {
+			t.Errorf("get %q should be found after a/b deleted", path)
+		}
+	}
+}
+
+func TestStorageCacheKey(t *testing.T) {
+	c := NewLRUCache(defaultCacheSize, time.Minute)
+	s := &Storage{workDir: "/", cache: c}
+	shared := &Storage{workDir: "/", cache: c, driveId: "drive"}
+
+	s.setCache("/a/b", "id-b")
+	for _, path := range []string{"a/b", "/a/b", "a/b/"} {
+		if id, found := s.getCache(path); !found || id != "id-b" {
+			t.Errorf("get %q = %q, %v, expect id-b, true", path, id, found)
+		}
+	}
+	if _, found := shared.getCache("a/b"); found {
+		t.Errorf("get a/b should not be found in another drive")
+	}
+
+	// Deleting the root should delete everything in the drive.
+	shared.setCache("a/b", "id-shared-b")
+	s.delCacheTree("/")
+	if _, found := s.getCache("a/b"); found {
+		t.Errorf("get a/b should not be found after root deleted")
+	}
+	if _, found := shared.getCache("a/b"); !found {
+		t.Errorf("get a/b should be found in another drive after root deleted")
+	}
+}
+
+// openFileCache opens the file cache at path, which will be closed after the test.
+func openFileCache(t *testing.T, path string, ttl time.Duration) FileCache {
+	c, err := NewFileCache(path, defaultCacheSize, ttl)
+	if err != nil {
+		t.Fatalf("open file cache: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Errorf("close file cache: %v", err)
+		}
+	})
+	return c
+}
+
+func TestFileCachePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "paths")
+
+	c := openFileCache(t, path, time.Minute)
+	for _, p := range []string{"a", "a/b", "a/b/c", "d", "e"} {
+		c.Set(p, "id-"+p)
+	}
+	c.Set("d", "id-new-d")
+	c.Delete("e")
+	c.DeleteTree("a/b")
+
+	// Reopen the cache to simulate a restart.
+	_ = c.Close()
+	c = openFileCache(t, path, time.Minute)
+	expects := map[string]string{"a": "id-a", "d": "id-new-d"}
+	for _, p := range []string{"a", "a/b", "a/b/c", "d", "e"} {
+		id, found := c.Get(p)
+		if expect, ok := expects[p]; id != expect || found != ok {
+			t.Errorf("get %q = %q, %v, expect %q, %v", p, id, found, expect, ok)
+		}
+	}
+}
+
+func TestFileCacheExpire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths")
+
+	c := openFileCache(t, path, 50*time.Millisecond)
+	c.Set("a", "id-a")
+
+	time.Sleep(100 * time.Millisecond)
+	_ = c.Close()
+	c = openFileCache(t, path, 50*time.Millisecond)
+	if _, found := c.Get("a"); found {
+		t.Errorf("get a should not be found after expired")
+	}
+}
+
+func TestFileCacheCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths")
+
+	c := openFileCache(t, path, time.Minute)
+	for i := 0; i < 10*fileCacheCompactThreshold; i++ {
+		c.Set("a", fmt.Sprintf("id-%d", i))
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	if n := strings.Count(string(content), "\n"); n > fileCacheCompactThreshold+1 {
+		t.Errorf("file has %d records, expect compacted", n)
+	}
+
+	_ = c.Close()
+	c = openFileCache(t, path, time.Minute)
+	if id, _ := c.Get("a"); id != fmt.Sprintf("id-%d", 10*fileCacheCompactThreshold-1) {
+		t.Errorf("get a = %q after compacted", id)
+	}
+}
+
+func TestFileCacheConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths")
+
+	c := openFileCache(t, path, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				c.Set("a", fmt.Sprintf("id-%d-%d", i, j))
+				c.DeleteTree("a")
+			}
+			c.Set("a", fmt.Sprintf("id-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	// The records should be appended in the same order as the changes made in memory.
+	expect, _ := c.Get("a")
+	_ = c.Close()
+	c = openFileCache(t, path, time.Minute)
+	if id, _ := c.Get("a"); id != expect {
+		t.Errorf("get a = %q after reopened, expect %q", id, expect)
+	}
+}
+
+// serveJSON responds content as JSON if the request matches method and path.
+func serveJSON(w http.ResponseWriter, r *http.Request, method, path, content string) bool {
+	if r.Method != method || r.URL.Path != path {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(content))
+	return true
+}
+
+// assertCached checks the cached fileId of paths, an empty fileId means the path should not be cached.
+func assertCached(t *testing.T, s *Storage, expect map[string]string) {
+	t.Helper()
+
+	for path, fileId := range expect {
+		id, found := s.getCache(path)
+		if fileId == "" && found {
+			t.Errorf("%s should not be cached, got %s", path, id)
+		}
+		if fileId != "" && id != fileId {
+			t.Errorf("%s should be cached as %s, got %q, %v", path, fileId, id, found)
+		}
+	}
+}
+
+func TestStorageCacheDelete(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveJSON(w, r, http.MethodDelete, "/drive/v3/files/id-dir", `{}`) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	store.setCache("/dir", "id-dir")
+	store.setCache("/dir/a", "id-a")
+	store.setCache("/dir/sub/b", "id-b")
+	store.setCache("/dirx", "id-dirx")
+
+	if err := store.Delete("dir"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	assertCached(t, store, map[string]string{
+		"/dir":       "",
+		"/dir/a":     "",
+		"/dir/sub/b": "",
+		"/dirx":      "id-dirx",
+	})
+}
+
+func TestStorageCacheMove(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveJSON(w, r, http.MethodDelete, "/drive/v3/files/id-dst", `{}`) ||
+			serveJSON(w, r, http.MethodGet, "/drive/v3/files/id-dst", `{"mimeType":"text/plain"}`) ||
+			serveJSON(w, r, http.MethodGet, "/drive/v3/files/id-src", `{"mimeType":"`+directoryMimeType+`","parents":["root"]}`) ||
+			serveJSON(w, r, http.MethodPatch, "/drive/v3/files/id-src", `{"id":"id-src"}`) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	store.setCache("/src", "id-src")
+	store.setCache("/src/a", "id-a")
+	store.setCache("/dst", "id-dst")
+	store.setCache("/dst/old", "id-old")
+
+	if err := store.Move("src", "dst"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	assertCached(t, store, map[string]string{
+		"/src":     "",
+		"/src/a":   "",
+		"/dst":     "id-src",
+		"/dst/old": "",
+	})
+}
+
+func TestStorageCacheCopy(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveJSON(w, r, http.MethodGet, "/drive/v3/files/id-src", `{"mimeType":"text/plain"}`) ||
+			serveJSON(w, r, http.MethodGet, "/drive/v3/files/id-dst", `{"mimeType":"text/plain"}`) ||
+			serveJSON(w, r, http.MethodDelete, "/drive/v3/files/id-dst", `{}`) ||
+			serveJSON(w, r, http.MethodPost, "/drive/v3/files/id-src/copy", `{"id":"id-copy"}`) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	store.setCache("/src", "id-src")
+	store.setCache("/dst", "id-dst")
+	store.setCache("/dst/old", "id-old")
+
+	if err := store.Copy("src", "dst"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	assertCached(t, store, map[string]string{
+		"/src":     "id-src",
+		"/dst":     "id-copy",
+		"/dst/old": "",
+	})
+}
+
+func TestStorageCacheWrite(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, false) ||
+			serveJSON(w, r, http.MethodPost, "/upload/drive/v3/files", `{"id":"id-new"}`) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	if _, err := store.Write("a", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	assertCached(t, store, map[string]string{"/a": "id-new"})
+}
+
+func TestStorageCacheCreateDir(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, false) {
+			return
+		}
+		if r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files" {
+			f := &drive.File{}
+			if err := json.NewDecoder(r.Body).Decode(f); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			serveJSON(w, r, r.Method, r.URL.Path, fmt.Sprintf(`{"id":"id-%s"}`, f.Name))
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	// A stale entry left by a deleted directory with the same path.
+	store.setCache("/a/b", "id-stale")
+
+	if _, err := store.CreateDir("a/b/c"); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	assertCached(t, store, map[string]string{
+		"/a":     "id-a",
+		"/a/b":   "id-b",
+		"/a/b/c": "id-c",
+	})
+}
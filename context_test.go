@@ -0,0 +1,281 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+)
+
+// cancelTimeout is how long we wait for an operation to return after canceled, the fake drive
+// will never respond the blocked requests, so returning in time means the request is aborted.
+const cancelTimeout = 2 * time.Second
+
+// newTestStorage creates a storage talking to a local fake drive served by h.
+func newTestStorage(t *testing.T, h http.HandlerFunc) *Storage {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	ds, err := drive.NewService(context.Background(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/drive/v3/"))
+	if err != nil {
+		t.Fatalf("new drive service: %v", err)
+	}
+	store, err := newStorage(pairStorageNew{Name: "test"}, srv.Client(), ds)
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+	return store
+}
+
+// serveSearch responds the files list request searching by name, the returned file's id
+// will be `id-<name>`. Nothing will be found if found is false.
+func serveSearch(w http.ResponseWriter, r *http.Request, found bool) bool {
+	q := r.URL.Query().Get("q")
+	if r.Method != http.MethodGet || r.URL.Path != "/drive/v3/files" || !strings.Contains(q, "name = ") {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !found {
+		_, _ = w.Write([]byte(`{"files":[]}`))
+		return true
+	}
+	name := strings.SplitN(q[strings.Index(q, "name = '")+len("name = '"):], "'", 2)[0]
+	_, _ = fmt.Fprintf(w, `{"files":[{"id":"id-%s","mimeType":"text/plain"}]}`, name)
+	return true
+}
+
+// block blocks the request until the client goes away, and notify started before blocking.
+func block(r *http.Request, started chan<- struct{}) {
+	// The server could only find the client gone after the body consumed.
+	_, _ = io.Copy(ioutil.Discard, r.Body)
+	close(started)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(time.Minute):
+	}
+}
+
+// cancelAfter cancels the ctx once started, and returns the time canceled.
+func cancelAfter(cancel context.CancelFunc, started <-chan struct{}) <-chan time.Time {
+	canceled := make(chan time.Time, 1)
+	go func() {
+		<-started
+		canceled <- time.Now()
+		cancel()
+	}()
+	return canceled
+}
+
+func assertCanceled(t *testing.T, err error, canceled <-chan time.Time) {
+	t.Helper()
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, expect context canceled", err)
+	}
+	select {
+	case at := <-canceled:
+		if d := time.Since(at); d > cancelTimeout {
+			t.Errorf("returned %v after canceled, expect abort promptly", d)
+		}
+	default:
+		t.Errorf("returned before canceled")
+	}
+}
+
+func TestDeleteCanceled(t *testing.T) {
+	started := make(chan struct{})
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, true) {
+			return
+		}
+		if r.Method == http.MethodDelete && r.URL.Path == "/drive/v3/files/id-a" {
+			block(r, started)
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	canceled := cancelAfter(cancel, started)
+
+	err := store.DeleteWithContext(ctx, "a")
+	assertCanceled(t, err, canceled)
+}
+
+func TestListCanceled(t *testing.T) {
+	started := make(chan struct{})
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, true) {
+			return
+		}
+		if r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files" {
+			block(r, started)
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	canceled := cancelAfter(cancel, started)
+
+	it, err := store.ListWithContext(ctx, "dir")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	_, err = it.Next()
+	assertCanceled(t, err, canceled)
+}
+
+// cancelWriter cancels the ctx once n bytes written.
+type cancelWriter struct {
+	buf    bytes.Buffer
+	n      int
+	cancel context.CancelFunc
+	// started will be closed once canceled.
+	started chan struct{}
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	if w.buf.Len() >= w.n && w.cancel != nil {
+		close(w.started)
+		w.cancel = nil
+	}
+	return n, err
+}
+
+func TestReadCanceledMidTransfer(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1024)
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, true) {
+			return
+		}
+		if r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/id-a" && r.URL.Query().Get("alt") == "media" {
+			w.Header().Set("Content-Length", "1048576")
+			_, _ = w.Write(content)
+			w.(http.Flusher).Flush()
+			block(r, make(chan struct{}))
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel after the content received, while the rest of body will never come.
+	w := &cancelWriter{n: len(content), cancel: cancel, started: make(chan struct{})}
+	canceled := cancelAfter(cancel, w.started)
+
+	n, err := store.ReadWithContext(ctx, "a", w)
+	assertCanceled(t, err, canceled)
+	if n != int64(len(content)) || !bytes.Equal(w.buf.Bytes(), content) {
+		t.Errorf("read %d bytes before canceled, expect %d", n, len(content))
+	}
+}
+
+// serveUploadSession starts an upload session, and persists every chunk put into it.
+// A chunk will be blocked if blockChunk returns true for it's offset.
+func serveUploadSession(t *testing.T, w http.ResponseWriter, r *http.Request, blockChunk func(offset int64) bool, started chan<- struct{}) bool {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files":
+		if r.URL.Query().Get("uploadType") != "resumable" {
+			t.Errorf("unexpected upload type %s", r.URL.Query().Get("uploadType"))
+		}
+		w.Header().Set("Location", "http://"+r.Host+"/upload/session?upload_id=test")
+		return true
+	case r.Method == http.MethodPut && r.URL.Path == "/upload/session":
+		var start, end, size int64
+		_, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size)
+		if err != nil {
+			t.Errorf("parse content range %q: %v", r.Header.Get("Content-Range"), err)
+		}
+		if blockChunk(start) {
+			block(r, started)
+			return true
+		}
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", end))
+		w.WriteHeader(statusResumeIncomplete)
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWriteCanceledMidChunk(t *testing.T) {
+	chunkSize := int64(googleapi.MinUploadChunkSize)
+	started := make(chan struct{})
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, false) {
+			return
+		}
+		if serveUploadSession(t, w, r, func(offset int64) bool { return offset == chunkSize }, started) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	canceled := cancelAfter(cancel, started)
+
+	size := 3 * chunkSize
+	_, err := store.WriteWithContext(ctx, "a", bytes.NewReader(make([]byte, size)), size, WithChunkSize(chunkSize))
+	assertCanceled(t, err, canceled)
+
+	// The session uri should be returned, so that the upload could be resumed.
+	var ie UploadInterruptedError
+	if !errors.As(err, &ie) || !strings.Contains(ie.SessionURI, "upload_id=test") {
+		t.Errorf("got %v, expect upload interrupted with session uri", err)
+	}
+}
+
+func TestWriteCanceledBetweenChunks(t *testing.T) {
+	chunkSize := int64(googleapi.MinUploadChunkSize)
+	var chunks int
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, false) {
+			return
+		}
+		if serveUploadSession(t, w, r, func(offset int64) bool {
+			chunks++
+			return false
+		}, nil) {
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	size := 3 * chunkSize
+	_, err := store.WriteWithContext(ctx, "a", bytes.NewReader(make([]byte, size)), size,
+		WithChunkSize(chunkSize),
+		// Cancel once the first chunk has been persisted.
+		ps.WithIoCallback(func([]byte) { cancel() }))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, expect context canceled", err)
+	}
+	if chunks != 1 {
+		t.Errorf("put %d chunks, expect no more chunk after canceled", chunks)
+	}
+}
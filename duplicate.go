@@ -0,0 +1,84 @@
+package gdrive
+
+import (
+	"sort"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Available values of duplicate_policy, which decides what to do if there are contents with
+// the same name in a folder.
+const (
+	// DuplicatePolicyError will return a DuplicatePathError.
+	DuplicatePolicyError = "error"
+	// DuplicatePolicyNewest will pick the content modified most recently.
+	DuplicatePolicyNewest = "newest"
+	// DuplicatePolicyOldest will pick the content created first.
+	DuplicatePolicyOldest = "oldest"
+	// DuplicatePolicyPreferFolder will pick folders before files, the newest one wins among them.
+	DuplicatePolicyPreferFolder = "prefer_folder"
+	// DuplicatePolicyPreferFile will pick files before folders, the newest one wins among them.
+	DuplicatePolicyPreferFile = "prefer_file"
+)
+
+// isDuplicatePolicyValid checks whether the policy is one we know.
+func isDuplicatePolicyValid(policy string) bool {
+	switch policy {
+	case DuplicatePolicyError, DuplicatePolicyNewest, DuplicatePolicyOldest,
+		DuplicatePolicyPreferFolder, DuplicatePolicyPreferFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveDuplicate will pick one of the files with the same name in a folder by the policy.
+// Files are compared by fileId at last, so that the result is stable.
+func resolveDuplicate(policy, name string, files []*drive.File) (*drive.File, error) {
+	if len(files) == 1 {
+		return files[0], nil
+	}
+
+	if policy == DuplicatePolicyError {
+		ids := make([]string, 0, len(files))
+		for _, f := range files {
+			ids = append(ids, f.Id)
+		}
+		return nil, DuplicatePathError{Name: name, FileIDs: ids}
+	}
+
+	files = append([]*drive.File(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		fi, fj := files[i], files[j]
+
+		switch policy {
+		case DuplicatePolicyOldest:
+			if ti, tj := parseFileTime(fi.CreatedTime), parseFileTime(fj.CreatedTime); !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return fi.Id < fj.Id
+		case DuplicatePolicyPreferFolder, DuplicatePolicyPreferFile:
+			di, dj := fi.MimeType == directoryMimeType, fj.MimeType == directoryMimeType
+			if di != dj {
+				return di == (policy == DuplicatePolicyPreferFolder)
+			}
+		}
+
+		if ti, tj := parseFileTime(fi.ModifiedTime), parseFileTime(fj.ModifiedTime); !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return fi.Id < fj.Id
+	})
+	return files[0], nil
+}
+
+// parseFileTime parses the RFC 3339 time returned by gdrive, zero time will be returned for
+// an invalid one.
+func parseFileTime(v string) time.Time {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,113 @@
+package gdrive
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/beyondstorage/go-storage/v4/services"
+)
+
+var (
+	// ErrCopyPartial will be returned if some contents failed to copy while copying a directory.
+	ErrCopyPartial = services.NewErrorCode("copy partial")
+	// ErrCopyIntoSelf will be returned while copying a directory into itself or it's sub directories.
+	ErrCopyIntoSelf = services.NewErrorCode("copy into self")
+	// ErrContentMd5Mismatch will be returned if the uploaded content doesn't match the content md5.
+	ErrContentMd5Mismatch = services.NewErrorCode("content md5 mismatch")
+	// ErrPartInvalid will be returned if the parts to complete don't match the written ones.
+	ErrPartInvalid = services.NewErrorCode("part invalid")
+	// ErrExportMimeTypeMissing will be returned if we don't know how to export a Google Workspace document.
+	ErrExportMimeTypeMissing = services.NewErrorCode("export mime type missing")
+	// ErrDriveNotExist will be returned if the shared drive with the name doesn't exist.
+	ErrDriveNotExist = services.NewErrorCode("drive not exist")
+	// ErrDuplicatePath will be returned if there are contents with the same name in a folder
+	// while duplicate_policy is error.
+	ErrDuplicatePath = services.NewErrorCode("duplicate path")
+	// ErrStorageQuotaExceeded will be returned if the storage quota of the user or the shared drive is used up.
+	ErrStorageQuotaExceeded = services.NewErrorCode("storage quota exceeded")
+	// ErrFileLimitExceeded will be returned if a shared drive or a folder has too many contents.
+	ErrFileLimitExceeded = services.NewErrorCode("file limit exceeded")
+	// ErrRootFolderUndeletable will be returned while deleting the root folder of My Drive or a shared drive.
+	ErrRootFolderUndeletable = services.NewErrorCode("root folder undeletable")
+	// ErrFileNotDownloadable will be returned while downloading a Google Workspace document, which could only be exported.
+	ErrFileNotDownloadable = services.NewErrorCode("file not downloadable")
+)
+
+// CopyPartialError means a directory copy has finished, but some of it's contents failed to copy.
+type CopyPartialError struct {
+	// Failed maps the src path of every failed content to the error we met.
+	Failed map[string]error
+}
+
+func (e CopyPartialError) Error() string {
+	paths := make([]string, 0, len(e.Failed))
+	for k := range e.Failed {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	return fmt.Sprintf("copy partial, failed paths %v: %s", paths, ErrCopyPartial.Error())
+}
+
+// Unwrap implements xerrors.Wrapper
+func (e CopyPartialError) Unwrap() error {
+	return ErrCopyPartial
+}
+
+// IsInternalError implements InternalError
+func (e CopyPartialError) IsInternalError() {}
+
+// ContentMd5MismatchError means the md5 checksum calculated by gdrive doesn't match the content md5 we expected.
+type ContentMd5MismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e ContentMd5MismatchError) Error() string {
+	return fmt.Sprintf("content md5 mismatch, expected %s, actual %s: %s", e.Expected, e.Actual, ErrContentMd5Mismatch.Error())
+}
+
+// Unwrap implements xerrors.Wrapper
+func (e ContentMd5MismatchError) Unwrap() error {
+	return ErrContentMd5Mismatch
+}
+
+// IsInternalError implements InternalError
+func (e ContentMd5MismatchError) IsInternalError() {}
+
+// DuplicatePathError means there are more than one content with the same name in a folder.
+type DuplicatePathError struct {
+	Name    string
+	FileIDs []string
+}
+
+func (e DuplicatePathError) Error() string {
+	return fmt.Sprintf("duplicate path, name %s, file ids %v: %s", e.Name, e.FileIDs, ErrDuplicatePath.Error())
+}
+
+// Unwrap implements xerrors.Wrapper
+func (e DuplicatePathError) Unwrap() error {
+	return ErrDuplicatePath
+}
+
+// IsInternalError implements InternalError
+func (e DuplicatePathError) IsInternalError() {}
+
+// UploadInterruptedError means a resumable upload failed in the middle, it could be resumed
+// by writing the same content again with the session uri.
+type UploadInterruptedError struct {
+	SessionURI string
+	Err        error
+}
+
+func (e UploadInterruptedError) Error() string {
+	return fmt.Sprintf("upload interrupted, session uri %s: %v", e.SessionURI, e.Err)
+}
+
+// Unwrap implements xerrors.Wrapper
+func (e UploadInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// IsInternalError implements InternalError
+func (e UploadInterruptedError) IsInternalError() {}
@@ -28,6 +28,15 @@ const Type = "gdrive"
 
 // ObjectSystemMetadata stores system metadata for object.
 type ObjectSystemMetadata struct {
+	FileID            string
+	HeadRevisionID    string
+	Md5Checksum       string
+	MimeType          string
+	Owners            []string
+	Trashed           bool
+	Version           int64
+	WebViewLink       string
+	WorkspaceDocument bool
 }
 
 // GetObjectSystemMetadata will get ObjectSystemMetadata from Object.
@@ -51,6 +60,15 @@ func setObjectSystemMetadata(o *Object, sm ObjectSystemMetadata) {
 
 // StorageSystemMetadata stores system metadata for object.
 type StorageSystemMetadata struct {
+	FileID            string
+	HeadRevisionID    string
+	Md5Checksum       string
+	MimeType          string
+	Owners            []string
+	Trashed           bool
+	Version           int64
+	WebViewLink       string
+	WorkspaceDocument bool
 }
 
 // GetStorageSystemMetadata will get StorageSystemMetadata from Storage.
@@ -72,21 +90,545 @@ func setStorageSystemMetadata(s *StorageMeta, sm StorageSystemMetadata) {
 	s.SetSystemMetadata(sm)
 }
 
+// WithCache will apply cache value to Options.
+//
+// specify the Cache to store the fileId of paths, e.g. NewFileCache to persist it, cache_size and
+// cache_ttl will be ignored if specified
+func WithCache(v Cache) Pair {
+	return Pair{Key: "cache", Value: v}
+}
+
+// WithCacheSize will apply cache_size value to Options.
+//
+// specify the max count of paths whose fileId will be cached in memory, default to 100000
+func WithCacheSize(v int64) Pair {
+	return Pair{Key: "cache_size", Value: v}
+}
+
+// WithCacheTTL will apply cache_ttl value to Options.
+//
+// specify how long the cached fileId of a path will be used, 0 means never expire, default to 1 minute
+func WithCacheTTL(v time.Duration) Pair {
+	return Pair{Key: "cache_ttl", Value: v}
+}
+
+// WithChunkSize will apply chunk_size value to Options.
+//
+// specify the chunk size of resumable upload, it will be rounded up to a multiple of 256KiB
+func WithChunkSize(v int64) Pair {
+	return Pair{Key: "chunk_size", Value: v}
+}
+
+// WithConvertMimeType will apply convert_mime_type value to Options.
+//
+// specify the Google Workspace document type to convert the content into while writing, content_type
+// should be the type of uploaded content
+func WithConvertMimeType(v string) Pair {
+	return Pair{Key: "convert_mime_type", Value: v}
+}
+
+// WithDefaultChunkSize will apply default_chunk_size value to Options.
+//
+// specify the chunk size of resumable upload, it will be rounded up to a multiple of 256KiB
+func WithDefaultChunkSize(v int64) Pair {
+	return Pair{Key: "default_chunk_size", Value: v}
+}
+
+// WithDefaultRetryDeadline will apply default_retry_deadline value to Options.
+//
+// specify how long a failed chunk will be retried before the resumable upload gives up
+func WithDefaultRetryDeadline(v time.Duration) Pair {
+	return Pair{Key: "default_retry_deadline", Value: v}
+}
+
+// WithDefaultServicePairs will apply default_service_pairs value to Options.
+func WithDefaultServicePairs(v DefaultServicePairs) Pair {
+	return Pair{Key: "default_service_pairs", Value: v}
+}
+
 // WithDefaultStoragePairs will apply default_storage_pairs value to Options.
 func WithDefaultStoragePairs(v DefaultStoragePairs) Pair {
 	return Pair{Key: "default_storage_pairs", Value: v}
 }
 
+// WithDriveID will apply drive_id value to Options.
+//
+// specify the id of the shared drive to use instead of My Drive
+func WithDriveID(v string) Pair {
+	return Pair{Key: "drive_id", Value: v}
+}
+
+// WithDuplicatePolicy will apply duplicate_policy value to Options.
+//
+// specify how to pick one of the contents with the same name in a folder, available values are error,
+// newest, oldest, prefer_folder and prefer_file, default to newest
+func WithDuplicatePolicy(v string) Pair {
+	return Pair{Key: "duplicate_policy", Value: v}
+}
+
+// WithExportMimeType will apply export_mime_type value to Options.
+//
+// specify the mime type to export a Google Workspace document into while reading
+func WithExportMimeType(v string) Pair {
+	return Pair{Key: "export_mime_type", Value: v}
+}
+
+// WithExportMimeTypes will apply export_mime_types value to Options.
+//
+// specify the mime types to export Google Workspace documents into by their mime types, which will
+// override the default ones
+func WithExportMimeTypes(v map[string]string) Pair {
+	return Pair{Key: "export_mime_types", Value: v}
+}
+
+// WithMediaRateLimit will apply media_rate_limit value to Options.
+//
+// specify the max requests per second to transfer file contents, shared by all storages and servicers
+// of the same user, the first one wins, default to unlimited
+func WithMediaRateLimit(v float64) Pair {
+	return Pair{Key: "media_rate_limit", Value: v}
+}
+
+// WithMetadataRateLimit will apply metadata_rate_limit value to Options.
+//
+// specify the max requests per second for metadata, shared by all storages and servicers of the same
+// user, the first one wins, default to unlimited
+func WithMetadataRateLimit(v float64) Pair {
+	return Pair{Key: "metadata_rate_limit", Value: v}
+}
+
+// WithRefreshToken will apply refresh_token value to Options.
+//
+// specify the refresh token of the user to authorize with, only supported with the credential of an
+// OAuth2 client, the token in token store is preferred
+func WithRefreshToken(v string) Pair {
+	return Pair{Key: "refresh_token", Value: v}
+}
+
+// WithRetryBaseDelay will apply retry_base_delay value to Options.
+//
+// specify the delay before the first retry of a failed request, it will be doubled for every following
+// retry, default to 1s
+func WithRetryBaseDelay(v time.Duration) Pair {
+	return Pair{Key: "retry_base_delay", Value: v}
+}
+
+// WithRetryDeadline will apply retry_deadline value to Options.
+//
+// specify how long a failed chunk will be retried before the resumable upload gives up
+func WithRetryDeadline(v time.Duration) Pair {
+	return Pair{Key: "retry_deadline", Value: v}
+}
+
+// WithRetryHook will apply retry_hook value to Options.
+//
+// specify the function to be called for every failed attempt of requests
+func WithRetryHook(v func(RetryAttempt)) Pair {
+	return Pair{Key: "retry_hook", Value: v}
+}
+
+// WithRetryJitter will apply retry_jitter value to Options.
+//
+// specify the fraction of retry delay to be randomized, between 0 and 1, default to 0.5
+func WithRetryJitter(v float64) Pair {
+	return Pair{Key: "retry_jitter", Value: v}
+}
+
+// WithRetryMaxAttempts will apply retry_max_attempts value to Options.
+//
+// specify the max attempts of a request, including the first one, 1 means never retry, default to 5
+func WithRetryMaxAttempts(v int) Pair {
+	return Pair{Key: "retry_max_attempts", Value: v}
+}
+
+// WithRetryMaxDelay will apply retry_max_delay value to Options.
+//
+// specify the max delay between retries of a failed request, default to 32s
+func WithRetryMaxDelay(v time.Duration) Pair {
+	return Pair{Key: "retry_max_delay", Value: v}
+}
+
+// WithServiceFeatures will apply service_features value to Options.
+func WithServiceFeatures(v ServiceFeatures) Pair {
+	return Pair{Key: "service_features", Value: v}
+}
+
 // WithStorageFeatures will apply storage_features value to Options.
 func WithStorageFeatures(v StorageFeatures) Pair {
 	return Pair{Key: "storage_features", Value: v}
 }
 
-var pairMap = map[string]string{"content_md5": "string", "content_type": "string", "context": "context.Context", "continuation_token": "string", "credential": "string", "default_content_type": "string", "default_io_callback": "func([]byte)", "default_storage_pairs": "DefaultStoragePairs", "endpoint": "string", "expire": "time.Duration", "http_client_options": "*httpclient.Options", "interceptor": "Interceptor", "io_callback": "func([]byte)", "list_mode": "ListMode", "location": "string", "multipart_id": "string", "name": "string", "object_mode": "ObjectMode", "offset": "int64", "size": "int64", "storage_features": "StorageFeatures", "work_dir": "string"}
+// WithTempDir will apply temp_dir value to Options.
+//
+// specify the dir to spill parts of multipart uploads and content of append objects, default to os.TempDir()
+func WithTempDir(v string) Pair {
+	return Pair{Key: "temp_dir", Value: v}
+}
+
+// WithTokenFile will apply token_file value to Options.
+//
+// specify the file to load the token of the user from and save the refreshed token into, which is a shortcut
+// of token_store with NewFileTokenStore, only supported with the credential of an OAuth2 client
+func WithTokenFile(v string) Pair {
+	return Pair{Key: "token_file", Value: v}
+}
+
+// WithTokenStore will apply token_store value to Options.
+//
+// specify the TokenStore to load the token of the user from and save the refreshed token into, only
+// supported with the credential of an OAuth2 client
+func WithTokenStore(v TokenStore) Pair {
+	return Pair{Key: "token_store", Value: v}
+}
+
+// WithTokenURL will apply token_url value to Options.
+//
+// specify the endpoint to refresh the token of the user, only used with the credential of an OAuth2
+// client, default to Google's
+func WithTokenURL(v string) Pair {
+	return Pair{Key: "token_url", Value: v}
+}
+
+// WithUploadSessionURI will apply upload_session_uri value to Options.
+//
+// specify the session uri of an interrupted resumable upload to resume, the reader must be io.Seeker
+// or io.ReaderAt
+func WithUploadSessionURI(v string) Pair {
+	return Pair{Key: "upload_session_uri", Value: v}
+}
+
+// WithWorkDirID will apply work_dir_id value to Options.
+//
+// specify the fileId of the work dir, paths will be resolved from it instead of the root, work_dir will
+// only be used to format paths
+func WithWorkDirID(v string) Pair {
+	return Pair{Key: "work_dir_id", Value: v}
+}
+
+var pairMap = map[string]string{"cache": "Cache", "cache_size": "int64", "cache_ttl": "time.Duration", "chunk_size": "int64", "content_md5": "string", "content_type": "string", "context": "context.Context", "continuation_token": "string", "convert_mime_type": "string", "credential": "string", "default_chunk_size": "int64", "default_content_type": "string", "default_io_callback": "func([]byte)", "default_retry_deadline": "time.Duration", "default_service_pairs": "DefaultServicePairs", "default_storage_pairs": "DefaultStoragePairs", "drive_id": "string", "duplicate_policy": "string", "endpoint": "string", "expire": "time.Duration", "export_mime_type": "string", "export_mime_types": "map[string]string", "http_client_options": "*httpclient.Options", "interceptor": "Interceptor", "io_callback": "func([]byte)", "list_mode": "ListMode", "location": "string", "media_rate_limit": "float64", "metadata_rate_limit": "float64", "multipart_id": "string", "name": "string", "object_mode": "ObjectMode", "offset": "int64", "refresh_token": "string", "retry_base_delay": "time.Duration", "retry_deadline": "time.Duration", "retry_hook": "func(RetryAttempt)", "retry_jitter": "float64", "retry_max_attempts": "int", "retry_max_delay": "time.Duration", "service_features": "ServiceFeatures", "size": "int64", "storage_features": "StorageFeatures", "temp_dir": "string", "token_file": "string", "token_store": "TokenStore", "token_url": "string", "upload_session_uri": "string", "work_dir": "string", "work_dir_id": "string"}
+var _ Servicer = &Service{}
+
+type ServiceFeatures struct {
+}
+
+// pairServiceNew is the parsed struct
+type pairServiceNew struct {
+	pairs []Pair
+
+	// Required pairs
+	HasCredential bool
+	Credential    string
+	// Optional pairs
+	HasDefaultServicePairs bool
+	DefaultServicePairs    DefaultServicePairs
+	HasHTTPClientOptions   bool
+	HTTPClientOptions      *httpclient.Options
+	HasMediaRateLimit      bool
+	MediaRateLimit         float64
+	HasMetadataRateLimit   bool
+	MetadataRateLimit      float64
+	HasRefreshToken        bool
+	RefreshToken           string
+	HasRetryBaseDelay      bool
+	RetryBaseDelay         time.Duration
+	HasRetryHook           bool
+	RetryHook              func(RetryAttempt)
+	HasRetryJitter         bool
+	RetryJitter            float64
+	HasRetryMaxAttempts    bool
+	RetryMaxAttempts       int
+	HasRetryMaxDelay       bool
+	RetryMaxDelay          time.Duration
+	HasServiceFeatures     bool
+	ServiceFeatures        ServiceFeatures
+	HasTokenFile           bool
+	TokenFile              string
+	HasTokenStore          bool
+	TokenStore             TokenStore
+	HasTokenURL            bool
+	TokenURL               string
+	// Enable features
+}
+
+// parsePairServiceNew will parse Pair slice into *pairServiceNew
+func parsePairServiceNew(opts []Pair) (pairServiceNew, error) {
+	result :=
+		pairServiceNew{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		case "credential":
+			if result.HasCredential {
+				continue
+			}
+			result.HasCredential = true
+			result.Credential = v.Value.(string)
+		case "default_service_pairs":
+			if result.HasDefaultServicePairs {
+				continue
+			}
+			result.HasDefaultServicePairs = true
+			result.DefaultServicePairs = v.Value.(DefaultServicePairs)
+		case "http_client_options":
+			if result.HasHTTPClientOptions {
+				continue
+			}
+			result.HasHTTPClientOptions = true
+			result.HTTPClientOptions = v.Value.(*httpclient.Options)
+		case "media_rate_limit":
+			if result.HasMediaRateLimit {
+				continue
+			}
+			result.HasMediaRateLimit = true
+			result.MediaRateLimit = v.Value.(float64)
+		case "metadata_rate_limit":
+			if result.HasMetadataRateLimit {
+				continue
+			}
+			result.HasMetadataRateLimit = true
+			result.MetadataRateLimit = v.Value.(float64)
+		case "refresh_token":
+			if result.HasRefreshToken {
+				continue
+			}
+			result.HasRefreshToken = true
+			result.RefreshToken = v.Value.(string)
+		case "retry_base_delay":
+			if result.HasRetryBaseDelay {
+				continue
+			}
+			result.HasRetryBaseDelay = true
+			result.RetryBaseDelay = v.Value.(time.Duration)
+		case "retry_hook":
+			if result.HasRetryHook {
+				continue
+			}
+			result.HasRetryHook = true
+			result.RetryHook = v.Value.(func(RetryAttempt))
+		case "retry_jitter":
+			if result.HasRetryJitter {
+				continue
+			}
+			result.HasRetryJitter = true
+			result.RetryJitter = v.Value.(float64)
+		case "retry_max_attempts":
+			if result.HasRetryMaxAttempts {
+				continue
+			}
+			result.HasRetryMaxAttempts = true
+			result.RetryMaxAttempts = v.Value.(int)
+		case "retry_max_delay":
+			if result.HasRetryMaxDelay {
+				continue
+			}
+			result.HasRetryMaxDelay = true
+			result.RetryMaxDelay = v.Value.(time.Duration)
+		case "service_features":
+			if result.HasServiceFeatures {
+				continue
+			}
+			result.HasServiceFeatures = true
+			result.ServiceFeatures = v.Value.(ServiceFeatures)
+		case "token_file":
+			if result.HasTokenFile {
+				continue
+			}
+			result.HasTokenFile = true
+			result.TokenFile = v.Value.(string)
+		case "token_store":
+			if result.HasTokenStore {
+				continue
+			}
+			result.HasTokenStore = true
+			result.TokenStore = v.Value.(TokenStore)
+		case "token_url":
+			if result.HasTokenURL {
+				continue
+			}
+			result.HasTokenURL = true
+			result.TokenURL = v.Value.(string)
+		}
+	}
+	// Enable features
+
+	// Default pairs
+
+	if !result.HasCredential {
+		return pairServiceNew{}, services.PairRequiredError{Keys: []string{"credential"}}
+	}
+	return result, nil
+}
+
+// DefaultServicePairs is default pairs for specific action
+type DefaultServicePairs struct {
+	Create []Pair
+	Delete []Pair
+	Get    []Pair
+	List   []Pair
+}
+type pairServiceCreate struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Service) parsePairServiceCreate(opts []Pair) (pairServiceCreate, error) {
+	result :=
+		pairServiceCreate{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairServiceCreate{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
+type pairServiceDelete struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Service) parsePairServiceDelete(opts []Pair) (pairServiceDelete, error) {
+	result :=
+		pairServiceDelete{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairServiceDelete{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
+type pairServiceGet struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Service) parsePairServiceGet(opts []Pair) (pairServiceGet, error) {
+	result :=
+		pairServiceGet{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairServiceGet{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
+type pairServiceList struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Service) parsePairServiceList(opts []Pair) (pairServiceList, error) {
+	result :=
+		pairServiceList{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairServiceList{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+func (s *Service) Create(name string, pairs ...Pair) (store Storager, err error) {
+	ctx := context.Background()
+	return s.CreateWithContext(ctx, name, pairs...)
+}
+func (s *Service) CreateWithContext(ctx context.Context, name string, pairs ...Pair) (store Storager, err error) {
+	defer func() {
+		err =
+			s.formatError("create", err, name)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Create...)
+	var opt pairServiceCreate
+
+	opt, err = s.parsePairServiceCreate(pairs)
+	if err != nil {
+		return
+	}
+	return s.create(ctx, name, opt)
+}
+func (s *Service) Delete(name string, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.DeleteWithContext(ctx, name, pairs...)
+}
+func (s *Service) DeleteWithContext(ctx context.Context, name string, pairs ...Pair) (err error) {
+	defer func() {
+		err =
+			s.formatError("delete", err, name)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Delete...)
+	var opt pairServiceDelete
+
+	opt, err = s.parsePairServiceDelete(pairs)
+	if err != nil {
+		return
+	}
+	return s.delete(ctx, name, opt)
+}
+func (s *Service) Get(name string, pairs ...Pair) (store Storager, err error) {
+	ctx := context.Background()
+	return s.GetWithContext(ctx, name, pairs...)
+}
+func (s *Service) GetWithContext(ctx context.Context, name string, pairs ...Pair) (store Storager, err error) {
+	defer func() {
+		err =
+			s.formatError("get", err, name)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Get...)
+	var opt pairServiceGet
+
+	opt, err = s.parsePairServiceGet(pairs)
+	if err != nil {
+		return
+	}
+	return s.get(ctx, name, opt)
+}
+func (s *Service) List(pairs ...Pair) (sti *StoragerIterator, err error) {
+	ctx := context.Background()
+	return s.ListWithContext(ctx, pairs...)
+}
+func (s *Service) ListWithContext(ctx context.Context, pairs ...Pair) (sti *StoragerIterator, err error) {
+	defer func() {
+		err =
+			s.formatError("list", err, "")
+	}()
+
+	pairs = append(pairs, s.defaultPairs.List...)
+	var opt pairServiceList
+
+	opt, err = s.parsePairServiceList(pairs)
+	if err != nil {
+		return
+	}
+	return s.list(ctx, opt)
+}
+
 var (
-	_ Copier   = &Storage{}
-	_ Direr    = &Storage{}
-	_ Storager = &Storage{}
+	_ Appender    = &Storage{}
+	_ Copier      = &Storage{}
+	_ Direr       = &Storage{}
+	_ Mover       = &Storage{}
+	_ Multiparter = &Storage{}
+	_ Storager    = &Storage{}
 )
 
 type StorageFeatures struct {
@@ -102,18 +644,60 @@ type pairStorageNew struct {
 	HasName       bool
 	Name          string
 	// Optional pairs
-	HasDefaultContentType  bool
-	DefaultContentType     string
-	HasDefaultIoCallback   bool
-	DefaultIoCallback      func([]byte)
-	HasDefaultStoragePairs bool
-	DefaultStoragePairs    DefaultStoragePairs
-	HasHTTPClientOptions   bool
-	HTTPClientOptions      *httpclient.Options
-	HasStorageFeatures     bool
-	StorageFeatures        StorageFeatures
-	HasWorkDir             bool
-	WorkDir                string
+	HasCache                bool
+	Cache                   Cache
+	HasCacheSize            bool
+	CacheSize               int64
+	HasCacheTTL             bool
+	CacheTTL                time.Duration
+	HasDefaultChunkSize     bool
+	DefaultChunkSize        int64
+	HasDefaultContentType   bool
+	DefaultContentType      string
+	HasDefaultIoCallback    bool
+	DefaultIoCallback       func([]byte)
+	HasDefaultRetryDeadline bool
+	DefaultRetryDeadline    time.Duration
+	HasDefaultStoragePairs  bool
+	DefaultStoragePairs     DefaultStoragePairs
+	HasDriveID              bool
+	DriveID                 string
+	HasDuplicatePolicy      bool
+	DuplicatePolicy         string
+	HasExportMimeTypes      bool
+	ExportMimeTypes         map[string]string
+	HasHTTPClientOptions    bool
+	HTTPClientOptions       *httpclient.Options
+	HasMediaRateLimit       bool
+	MediaRateLimit          float64
+	HasMetadataRateLimit    bool
+	MetadataRateLimit       float64
+	HasRefreshToken         bool
+	RefreshToken            string
+	HasRetryBaseDelay       bool
+	RetryBaseDelay          time.Duration
+	HasRetryHook            bool
+	RetryHook               func(RetryAttempt)
+	HasRetryJitter          bool
+	RetryJitter             float64
+	HasRetryMaxAttempts     bool
+	RetryMaxAttempts        int
+	HasRetryMaxDelay        bool
+	RetryMaxDelay           time.Duration
+	HasStorageFeatures      bool
+	StorageFeatures         StorageFeatures
+	HasTempDir              bool
+	TempDir                 string
+	HasTokenFile            bool
+	TokenFile               string
+	HasTokenStore           bool
+	TokenStore              TokenStore
+	HasTokenURL             bool
+	TokenURL                string
+	HasWorkDir              bool
+	WorkDir                 string
+	HasWorkDirID            bool
+	WorkDirID               string
 	// Enable features
 }
 
@@ -136,6 +720,30 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 			}
 			result.HasName = true
 			result.Name = v.Value.(string)
+		case "cache":
+			if result.HasCache {
+				continue
+			}
+			result.HasCache = true
+			result.Cache = v.Value.(Cache)
+		case "cache_size":
+			if result.HasCacheSize {
+				continue
+			}
+			result.HasCacheSize = true
+			result.CacheSize = v.Value.(int64)
+		case "cache_ttl":
+			if result.HasCacheTTL {
+				continue
+			}
+			result.HasCacheTTL = true
+			result.CacheTTL = v.Value.(time.Duration)
+		case "default_chunk_size":
+			if result.HasDefaultChunkSize {
+				continue
+			}
+			result.HasDefaultChunkSize = true
+			result.DefaultChunkSize = v.Value.(int64)
 		case "default_content_type":
 			if result.HasDefaultContentType {
 				continue
@@ -148,35 +756,141 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 			}
 			result.HasDefaultIoCallback = true
 			result.DefaultIoCallback = v.Value.(func([]byte))
+		case "default_retry_deadline":
+			if result.HasDefaultRetryDeadline {
+				continue
+			}
+			result.HasDefaultRetryDeadline = true
+			result.DefaultRetryDeadline = v.Value.(time.Duration)
 		case "default_storage_pairs":
 			if result.HasDefaultStoragePairs {
 				continue
 			}
 			result.HasDefaultStoragePairs = true
 			result.DefaultStoragePairs = v.Value.(DefaultStoragePairs)
+		case "drive_id":
+			if result.HasDriveID {
+				continue
+			}
+			result.HasDriveID = true
+			result.DriveID = v.Value.(string)
+		case "duplicate_policy":
+			if result.HasDuplicatePolicy {
+				continue
+			}
+			result.HasDuplicatePolicy = true
+			result.DuplicatePolicy = v.Value.(string)
+		case "export_mime_types":
+			if result.HasExportMimeTypes {
+				continue
+			}
+			result.HasExportMimeTypes = true
+			result.ExportMimeTypes = v.Value.(map[string]string)
 		case "http_client_options":
 			if result.HasHTTPClientOptions {
 				continue
 			}
-			result.HasHTTPClientOptions = true
-			result.HTTPClientOptions = v.Value.(*httpclient.Options)
+			result.HasHTTPClientOptions = true
+			result.HTTPClientOptions = v.Value.(*httpclient.Options)
+		case "media_rate_limit":
+			if result.HasMediaRateLimit {
+				continue
+			}
+			result.HasMediaRateLimit = true
+			result.MediaRateLimit = v.Value.(float64)
+		case "metadata_rate_limit":
+			if result.HasMetadataRateLimit {
+				continue
+			}
+			result.HasMetadataRateLimit = true
+			result.MetadataRateLimit = v.Value.(float64)
+		case "refresh_token":
+			if result.HasRefreshToken {
+				continue
+			}
+			result.HasRefreshToken = true
+			result.RefreshToken = v.Value.(string)
+		case "retry_base_delay":
+			if result.HasRetryBaseDelay {
+				continue
+			}
+			result.HasRetryBaseDelay = true
+			result.RetryBaseDelay = v.Value.(time.Duration)
+		case "retry_hook":
+			if result.HasRetryHook {
+				continue
+			}
+			result.HasRetryHook = true
+			result.RetryHook = v.Value.(func(RetryAttempt))
+		case "retry_jitter":
+			if result.HasRetryJitter {
+				continue
+			}
+			result.HasRetryJitter = true
+			result.RetryJitter = v.Value.(float64)
+		case "retry_max_attempts":
+			if result.HasRetryMaxAttempts {
+				continue
+			}
+			result.HasRetryMaxAttempts = true
+			result.RetryMaxAttempts = v.Value.(int)
+		case "retry_max_delay":
+			if result.HasRetryMaxDelay {
+				continue
+			}
+			result.HasRetryMaxDelay = true
+			result.RetryMaxDelay = v.Value.(time.Duration)
 		case "storage_features":
 			if result.HasStorageFeatures {
 				continue
 			}
 			result.HasStorageFeatures = true
 			result.StorageFeatures = v.Value.(StorageFeatures)
+		case "temp_dir":
+			if result.HasTempDir {
+				continue
+			}
+			result.HasTempDir = true
+			result.TempDir = v.Value.(string)
+		case "token_file":
+			if result.HasTokenFile {
+				continue
+			}
+			result.HasTokenFile = true
+			result.TokenFile = v.Value.(string)
+		case "token_store":
+			if result.HasTokenStore {
+				continue
+			}
+			result.HasTokenStore = true
+			result.TokenStore = v.Value.(TokenStore)
+		case "token_url":
+			if result.HasTokenURL {
+				continue
+			}
+			result.HasTokenURL = true
+			result.TokenURL = v.Value.(string)
 		case "work_dir":
 			if result.HasWorkDir {
 				continue
 			}
 			result.HasWorkDir = true
 			result.WorkDir = v.Value.(string)
+		case "work_dir_id":
+			if result.HasWorkDirID {
+				continue
+			}
+			result.HasWorkDirID = true
+			result.WorkDirID = v.Value.(string)
 		}
 	}
 	// Enable features
 
 	// Default pairs
+	if result.HasDefaultChunkSize {
+		result.HasDefaultStoragePairs = true
+		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithChunkSize(result.DefaultChunkSize))
+	}
 	if result.HasDefaultContentType {
 		result.HasDefaultStoragePairs = true
 		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithContentType(result.DefaultContentType))
@@ -186,6 +900,10 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 		result.DefaultStoragePairs.Read = append(result.DefaultStoragePairs.Read, WithIoCallback(result.DefaultIoCallback))
 		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithIoCallback(result.DefaultIoCallback))
 	}
+	if result.HasDefaultRetryDeadline {
+		result.HasDefaultStoragePairs = true
+		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithRetryDeadline(result.DefaultRetryDeadline))
+	}
 	if !result.HasCredential {
 		return pairStorageNew{}, services.PairRequiredError{Keys: []string{"credential"}}
 	}
@@ -197,16 +915,64 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 
 // DefaultStoragePairs is default pairs for specific action
 type DefaultStoragePairs struct {
-	Copy      []Pair
-	Create    []Pair
-	CreateDir []Pair
-	Delete    []Pair
-	List      []Pair
-	Metadata  []Pair
-	Read      []Pair
-	Stat      []Pair
-	Write     []Pair
+	CommitAppend      []Pair
+	CompleteMultipart []Pair
+	Copy              []Pair
+	Create            []Pair
+	CreateAppend      []Pair
+	CreateDir         []Pair
+	CreateMultipart   []Pair
+	Delete            []Pair
+	List              []Pair
+	ListMultipart     []Pair
+	Metadata          []Pair
+	Move              []Pair
+	Read              []Pair
+	Stat              []Pair
+	Write             []Pair
+	WriteAppend       []Pair
+	WriteMultipart    []Pair
+}
+type pairStorageCommitAppend struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageCommitAppend(opts []Pair) (pairStorageCommitAppend, error) {
+	result :=
+		pairStorageCommitAppend{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageCommitAppend{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
 }
+
+type pairStorageCompleteMultipart struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageCompleteMultipart(opts []Pair) (pairStorageCompleteMultipart, error) {
+	result :=
+		pairStorageCompleteMultipart{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageCompleteMultipart{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
 type pairStorageCopy struct {
 	pairs []Pair
 	// Required pairs
@@ -231,8 +997,10 @@ type pairStorageCreate struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasObjectMode bool
-	ObjectMode    ObjectMode
+	HasMultipartID bool
+	MultipartID    string
+	HasObjectMode  bool
+	ObjectMode     ObjectMode
 }
 
 func (s *Storage) parsePairStorageCreate(opts []Pair) (pairStorageCreate, error) {
@@ -241,6 +1009,12 @@ func (s *Storage) parsePairStorageCreate(opts []Pair) (pairStorageCreate, error)
 
 	for _, v := range opts {
 		switch v.Key {
+		case "multipart_id":
+			if result.HasMultipartID {
+				continue
+			}
+			result.HasMultipartID = true
+			result.MultipartID = v.Value.(string)
 		case "object_mode":
 			if result.HasObjectMode {
 				continue
@@ -255,6 +1029,26 @@ func (s *Storage) parsePairStorageCreate(opts []Pair) (pairStorageCreate, error)
 	return result, nil
 }
 
+type pairStorageCreateAppend struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageCreateAppend(opts []Pair) (pairStorageCreateAppend, error) {
+	result :=
+		pairStorageCreateAppend{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageCreateAppend{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
 type pairStorageCreateDir struct {
 	pairs []Pair
 	// Required pairs
@@ -275,12 +1069,34 @@ func (s *Storage) parsePairStorageCreateDir(opts []Pair) (pairStorageCreateDir,
 	return result, nil
 }
 
+type pairStorageCreateMultipart struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageCreateMultipart(opts []Pair) (pairStorageCreateMultipart, error) {
+	result :=
+		pairStorageCreateMultipart{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageCreateMultipart{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
 type pairStorageDelete struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasObjectMode bool
-	ObjectMode    ObjectMode
+	HasMultipartID bool
+	MultipartID    string
+	HasObjectMode  bool
+	ObjectMode     ObjectMode
 }
 
 func (s *Storage) parsePairStorageDelete(opts []Pair) (pairStorageDelete, error) {
@@ -289,6 +1105,12 @@ func (s *Storage) parsePairStorageDelete(opts []Pair) (pairStorageDelete, error)
 
 	for _, v := range opts {
 		switch v.Key {
+		case "multipart_id":
+			if result.HasMultipartID {
+				continue
+			}
+			result.HasMultipartID = true
+			result.MultipartID = v.Value.(string)
 		case "object_mode":
 			if result.HasObjectMode {
 				continue
@@ -307,8 +1129,10 @@ type pairStorageList struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasListMode bool
-	ListMode    ListMode
+	HasContinuationToken bool
+	ContinuationToken    string
+	HasListMode          bool
+	ListMode             ListMode
 }
 
 func (s *Storage) parsePairStorageList(opts []Pair) (pairStorageList, error) {
@@ -317,6 +1141,12 @@ func (s *Storage) parsePairStorageList(opts []Pair) (pairStorageList, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "continuation_token":
+			if result.HasContinuationToken {
+				continue
+			}
+			result.HasContinuationToken = true
+			result.ContinuationToken = v.Value.(string)
 		case "list_mode":
 			if result.HasListMode {
 				continue
@@ -331,6 +1161,26 @@ func (s *Storage) parsePairStorageList(opts []Pair) (pairStorageList, error) {
 	return result, nil
 }
 
+type pairStorageListMultipart struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageListMultipart(opts []Pair) (pairStorageListMultipart, error) {
+	result :=
+		pairStorageListMultipart{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageListMultipart{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
 type pairStorageMetadata struct {
 	pairs []Pair
 	// Required pairs
@@ -351,16 +1201,38 @@ func (s *Storage) parsePairStorageMetadata(opts []Pair) (pairStorageMetadata, er
 	return result, nil
 }
 
+type pairStorageMove struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageMove(opts []Pair) (pairStorageMove, error) {
+	result :=
+		pairStorageMove{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageMove{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
 type pairStorageRead struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasIoCallback bool
-	IoCallback    func([]byte)
-	HasOffset     bool
-	Offset        int64
-	HasSize       bool
-	Size          int64
+	HasExportMimeType bool
+	ExportMimeType    string
+	HasIoCallback     bool
+	IoCallback        func([]byte)
+	HasOffset         bool
+	Offset            int64
+	HasSize           bool
+	Size              int64
 }
 
 func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
@@ -369,6 +1241,12 @@ func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "export_mime_type":
+			if result.HasExportMimeType {
+				continue
+			}
+			result.HasExportMimeType = true
+			result.ExportMimeType = v.Value.(string)
 		case "io_callback":
 			if result.HasIoCallback {
 				continue
@@ -399,8 +1277,10 @@ type pairStorageStat struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasObjectMode bool
-	ObjectMode    ObjectMode
+	HasMultipartID bool
+	MultipartID    string
+	HasObjectMode  bool
+	ObjectMode     ObjectMode
 }
 
 func (s *Storage) parsePairStorageStat(opts []Pair) (pairStorageStat, error) {
@@ -409,6 +1289,12 @@ func (s *Storage) parsePairStorageStat(opts []Pair) (pairStorageStat, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "multipart_id":
+			if result.HasMultipartID {
+				continue
+			}
+			result.HasMultipartID = true
+			result.MultipartID = v.Value.(string)
 		case "object_mode":
 			if result.HasObjectMode {
 				continue
@@ -427,12 +1313,20 @@ type pairStorageWrite struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasContentMd5  bool
-	ContentMd5     string
-	HasContentType bool
-	ContentType    string
-	HasIoCallback  bool
-	IoCallback     func([]byte)
+	HasChunkSize        bool
+	ChunkSize           int64
+	HasContentMd5       bool
+	ContentMd5          string
+	HasContentType      bool
+	ContentType         string
+	HasConvertMimeType  bool
+	ConvertMimeType     string
+	HasIoCallback       bool
+	IoCallback          func([]byte)
+	HasRetryDeadline    bool
+	RetryDeadline       time.Duration
+	HasUploadSessionURI bool
+	UploadSessionURI    string
 }
 
 func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
@@ -441,6 +1335,12 @@ func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "chunk_size":
+			if result.HasChunkSize {
+				continue
+			}
+			result.HasChunkSize = true
+			result.ChunkSize = v.Value.(int64)
 		case "content_md5":
 			if result.HasContentMd5 {
 				continue
@@ -453,12 +1353,30 @@ func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
 			}
 			result.HasContentType = true
 			result.ContentType = v.Value.(string)
+		case "convert_mime_type":
+			if result.HasConvertMimeType {
+				continue
+			}
+			result.HasConvertMimeType = true
+			result.ConvertMimeType = v.Value.(string)
 		case "io_callback":
 			if result.HasIoCallback {
 				continue
 			}
 			result.HasIoCallback = true
 			result.IoCallback = v.Value.(func([]byte))
+		case "retry_deadline":
+			if result.HasRetryDeadline {
+				continue
+			}
+			result.HasRetryDeadline = true
+			result.RetryDeadline = v.Value.(time.Duration)
+		case "upload_session_uri":
+			if result.HasUploadSessionURI {
+				continue
+			}
+			result.HasUploadSessionURI = true
+			result.UploadSessionURI = v.Value.(string)
 		default:
 			return pairStorageWrite{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -466,6 +1384,90 @@ func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
 
 	return result, nil
 }
+
+type pairStorageWriteAppend struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageWriteAppend(opts []Pair) (pairStorageWriteAppend, error) {
+	result :=
+		pairStorageWriteAppend{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageWriteAppend{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+
+type pairStorageWriteMultipart struct {
+	pairs []Pair
+	// Required pairs
+	// Optional pairs
+}
+
+func (s *Storage) parsePairStorageWriteMultipart(opts []Pair) (pairStorageWriteMultipart, error) {
+	result :=
+		pairStorageWriteMultipart{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		default:
+			return pairStorageWriteMultipart{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+
+	return result, nil
+}
+func (s *Storage) CommitAppend(o *Object, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.CommitAppendWithContext(ctx, o, pairs...)
+}
+func (s *Storage) CommitAppendWithContext(ctx context.Context, o *Object, pairs ...Pair) (err error) {
+	defer func() {
+		err =
+			s.formatError("commit_append", err)
+	}()
+	if !o.Mode.IsAppend() {
+		err = services.ObjectModeInvalidError{Expected: ModeAppend, Actual: o.Mode}
+		return
+	}
+	pairs = append(pairs, s.defaultPairs.CommitAppend...)
+	var opt pairStorageCommitAppend
+
+	opt, err = s.parsePairStorageCommitAppend(pairs)
+	if err != nil {
+		return
+	}
+	return s.commitAppend(ctx, o, opt)
+}
+func (s *Storage) CompleteMultipart(o *Object, parts []*Part, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.CompleteMultipartWithContext(ctx, o, parts, pairs...)
+}
+func (s *Storage) CompleteMultipartWithContext(ctx context.Context, o *Object, parts []*Part, pairs ...Pair) (err error) {
+	defer func() {
+		err =
+			s.formatError("complete_multipart", err)
+	}()
+	if !o.Mode.IsPart() {
+		err = services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+		return
+	}
+	pairs = append(pairs, s.defaultPairs.CompleteMultipart...)
+	var opt pairStorageCompleteMultipart
+
+	opt, err = s.parsePairStorageCompleteMultipart(pairs)
+	if err != nil {
+		return
+	}
+	return s.completeMultipart(ctx, o, parts, opt)
+}
 func (s *Storage) Copy(src string, dst string, pairs ...Pair) (err error) {
 	ctx := context.Background()
 	return s.CopyWithContext(ctx, src, dst, pairs...)
@@ -493,6 +1495,25 @@ func (s *Storage) Create(path string, pairs ...Pair) (o *Object) {
 	opt, _ = s.parsePairStorageCreate(pairs)
 	return s.create(path, opt)
 }
+func (s *Storage) CreateAppend(path string, pairs ...Pair) (o *Object, err error) {
+	ctx := context.Background()
+	return s.CreateAppendWithContext(ctx, path, pairs...)
+}
+func (s *Storage) CreateAppendWithContext(ctx context.Context, path string, pairs ...Pair) (o *Object, err error) {
+	defer func() {
+		err =
+			s.formatError("create_append", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.CreateAppend...)
+	var opt pairStorageCreateAppend
+
+	opt, err = s.parsePairStorageCreateAppend(pairs)
+	if err != nil {
+		return
+	}
+	return s.createAppend(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
+}
 func (s *Storage) CreateDir(path string, pairs ...Pair) (o *Object, err error) {
 	ctx := context.Background()
 	return s.CreateDirWithContext(ctx, path, pairs...)
@@ -512,6 +1533,25 @@ func (s *Storage) CreateDirWithContext(ctx context.Context, path string, pairs .
 	}
 	return s.createDir(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
 }
+func (s *Storage) CreateMultipart(path string, pairs ...Pair) (o *Object, err error) {
+	ctx := context.Background()
+	return s.CreateMultipartWithContext(ctx, path, pairs...)
+}
+func (s *Storage) CreateMultipartWithContext(ctx context.Context, path string, pairs ...Pair) (o *Object, err error) {
+	defer func() {
+		err =
+			s.formatError("create_multipart", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.CreateMultipart...)
+	var opt pairStorageCreateMultipart
+
+	opt, err = s.parsePairStorageCreateMultipart(pairs)
+	if err != nil {
+		return
+	}
+	return s.createMultipart(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
+}
 func (s *Storage) Delete(path string, pairs ...Pair) (err error) {
 	ctx := context.Background()
 	return s.DeleteWithContext(ctx, path, pairs...)
@@ -550,6 +1590,28 @@ func (s *Storage) ListWithContext(ctx context.Context, path string, pairs ...Pai
 	}
 	return s.list(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
 }
+func (s *Storage) ListMultipart(o *Object, pairs ...Pair) (pi *PartIterator, err error) {
+	ctx := context.Background()
+	return s.ListMultipartWithContext(ctx, o, pairs...)
+}
+func (s *Storage) ListMultipartWithContext(ctx context.Context, o *Object, pairs ...Pair) (pi *PartIterator, err error) {
+	defer func() {
+		err =
+			s.formatError("list_multipart", err)
+	}()
+	if !o.Mode.IsPart() {
+		err = services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+		return
+	}
+	pairs = append(pairs, s.defaultPairs.ListMultipart...)
+	var opt pairStorageListMultipart
+
+	opt, err = s.parsePairStorageListMultipart(pairs)
+	if err != nil {
+		return
+	}
+	return s.listMultipart(ctx, o, opt)
+}
 func (s *Storage) Metadata(pairs ...Pair) (meta *StorageMeta) {
 	pairs = append(pairs, s.defaultPairs.Metadata...)
 	var opt pairStorageMetadata
@@ -558,6 +1620,25 @@ func (s *Storage) Metadata(pairs ...Pair) (meta *StorageMeta) {
 	opt, _ = s.parsePairStorageMetadata(pairs)
 	return s.metadata(opt)
 }
+func (s *Storage) Move(src string, dst string, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.MoveWithContext(ctx, src, dst, pairs...)
+}
+func (s *Storage) MoveWithContext(ctx context.Context, src string, dst string, pairs ...Pair) (err error) {
+	defer func() {
+		err =
+			s.formatError("move", err, src, dst)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Move...)
+	var opt pairStorageMove
+
+	opt, err = s.parsePairStorageMove(pairs)
+	if err != nil {
+		return
+	}
+	return s.move(ctx, strings.ReplaceAll(src, "\\", "/"), strings.ReplaceAll(dst, "\\", "/"), opt)
+}
 func (s *Storage) Read(path string, w io.Writer, pairs ...Pair) (n int64, err error) {
 	ctx := context.Background()
 	return s.ReadWithContext(ctx, path, w, pairs...)
@@ -615,7 +1696,52 @@ func (s *Storage) WriteWithContext(ctx context.Context, path string, r io.Reader
 	}
 	return s.write(ctx, strings.ReplaceAll(path, "\\", "/"), r, size, opt)
 }
+func (s *Storage) WriteAppend(o *Object, r io.Reader, size int64, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.WriteAppendWithContext(ctx, o, r, size, pairs...)
+}
+func (s *Storage) WriteAppendWithContext(ctx context.Context, o *Object, r io.Reader, size int64, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err =
+			s.formatError("write_append", err)
+	}()
+	if !o.Mode.IsAppend() {
+		err = services.ObjectModeInvalidError{Expected: ModeAppend, Actual: o.Mode}
+		return
+	}
+	pairs = append(pairs, s.defaultPairs.WriteAppend...)
+	var opt pairStorageWriteAppend
+
+	opt, err = s.parsePairStorageWriteAppend(pairs)
+	if err != nil {
+		return
+	}
+	return s.writeAppend(ctx, o, r, size, opt)
+}
+func (s *Storage) WriteMultipart(o *Object, r io.Reader, size int64, index int, pairs ...Pair) (n int64, part *Part, err error) {
+	ctx := context.Background()
+	return s.WriteMultipartWithContext(ctx, o, r, size, index, pairs...)
+}
+func (s *Storage) WriteMultipartWithContext(ctx context.Context, o *Object, r io.Reader, size int64, index int, pairs ...Pair) (n int64, part *Part, err error) {
+	defer func() {
+		err =
+			s.formatError("write_multipart", err)
+	}()
+	if !o.Mode.IsPart() {
+		err = services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+		return
+	}
+	pairs = append(pairs, s.defaultPairs.WriteMultipart...)
+	var opt pairStorageWriteMultipart
+
+	opt, err = s.parsePairStorageWriteMultipart(pairs)
+	if err != nil {
+		return
+	}
+	return s.writeMultipart(ctx, o, r, size, index, opt)
+}
 func init() {
+	services.RegisterServicer(Type, NewServicer)
 	services.RegisterStorager(Type, NewStorager)
 	services.RegisterSchema(Type, pairMap)
 }
@@ -5,7 +5,6 @@ go 1.15
 require (
 	github.com/beyondstorage/go-integration-test/v4 v4.6.0
 	github.com/beyondstorage/go-storage/v4 v4.8.0
-	github.com/dgraph-io/ristretto v0.1.0
 	github.com/google/uuid v1.3.0
 	golang.org/x/oauth2 v0.0.0-20211005180243-6b3c2da341f1
 	google.golang.org/api v0.59.0
@@ -1,11 +1,81 @@
 package gdrive
 
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
 type objectPageStatus struct {
 	limit     uint32
 	path      string
 	pageToken string
+
+	// Only used in prefix list mode.
+	prefix bool
+	// dirs is the queue of directories waiting to be listed, the head is the one being listed.
+	dirs []dirPageStatus
+}
+
+// dirPageStatus stores a directory to be listed in prefix list mode.
+type dirPageStatus struct {
+	Id   string `json:"id"`
+	Path string `json:"path"`
+	// NamePrefix filters the direct contents of this directory, only contents
+	// whose name has this prefix will be listed.
+	NamePrefix string `json:"name_prefix,omitempty"`
+}
+
+// prefixContinuationToken is the decoded continuation token of prefix list mode.
+type prefixContinuationToken struct {
+	PageToken string          `json:"page_token,omitempty"`
+	Dirs      []dirPageStatus `json:"dirs"`
 }
 
 func (i *objectPageStatus) ContinuationToken() string {
+	if !i.prefix {
+		return i.pageToken
+	}
+
+	// Walk has been finished, there is nothing to resume.
+	if len(i.dirs) == 0 {
+		return ""
+	}
+
+	content, _ := json.Marshal(prefixContinuationToken{
+		PageToken: i.pageToken,
+		Dirs:      i.dirs,
+	})
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+type storagePageStatus struct {
+	pageToken string
+}
+
+func (i *storagePageStatus) ContinuationToken() string {
 	return i.pageToken
 }
+
+type partPageStatus struct {
+	// dir is the spill dir of the multipart.
+	dir string
+}
+
+func (i *partPageStatus) ContinuationToken() string {
+	return ""
+}
+
+// parsePrefixContinuationToken will restore the walk status from a continuation
+// token returned by prefix list mode.
+func parsePrefixContinuationToken(token string) (t prefixContinuationToken, err error) {
+	content, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return t, err
+	}
+
+	err = json.Unmarshal(content, &t)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
@@ -0,0 +1,220 @@
+package gdrive
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/beyondstorage/go-storage/v4/types"
+)
+
+const (
+	multipartRootName = "go-service-gdrive"
+	multipartMetaName = "meta.json"
+	// partTempPattern is the pattern of a part which is still being spilled.
+	partTempPattern = "part-*"
+)
+
+// multipartMeta will be persisted in the spill dir of a multipart, so that the
+// multipart could be found again after the process restarted.
+type multipartMeta struct {
+	// Path is the abs path of the object.
+	Path        string `json:"path"`
+	MultipartID string `json:"multipart_id"`
+}
+
+// getMultipartRoot returns the dir which holds the spill dirs of all multiparts of the storage.
+// Storages sharing the same temp dir are separated by their names and work dirs, so that they
+// will never list or complete the multiparts of each other.
+func (s *Storage) getMultipartRoot() string {
+	sum := sha256.Sum256([]byte(s.name + ":" + s.workDir + ":" + s.getWorkDirId()))
+	return filepath.Join(s.tempDir, multipartRootName, hex.EncodeToString(sum[:]))
+}
+
+// getMultipartDir returns the dir to spill the parts of a multipart.
+func (s *Storage) getMultipartDir(multipartID string) string {
+	sum := sha256.Sum256([]byte(multipartID))
+	return filepath.Join(s.getMultipartRoot(), hex.EncodeToString(sum[:]))
+}
+
+func writeMultipartMeta(dir string, meta multipartMeta) (err error) {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, multipartMetaName), content, 0600)
+}
+
+func readMultipartMeta(dir string) (meta multipartMeta, err error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, multipartMetaName))
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(content, &meta)
+	if err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// getPartPath returns the path of a spilled part, the name of which looks like `<index>.<etag>`.
+func getPartPath(dir string, p *types.Part) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.%s", p.Index, p.ETag))
+}
+
+// spillPart will write the content of a part into the spill dir.
+// The part is written into a temp file first, and renamed after all content written, so
+// that we will never see a broken part.
+func spillPart(dir string, r io.Reader, size int64, index int) (p *types.Part, err error) {
+	f, err := ioutil.TempFile(dir, partTempPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r, size))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("part %d expected %d bytes, actual %d: %w", index, size, n, io.ErrUnexpectedEOF)
+	}
+
+	p = &types.Part{
+		Index: index,
+		Size:  size,
+		ETag:  hex.EncodeToString(h.Sum(nil)),
+	}
+
+	// Remove the previous content of the same part.
+	olds, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%d.*", index)))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range olds {
+		err = os.Remove(v)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	err = os.Rename(f.Name(), getPartPath(dir, p))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// listSpilledParts returns all the spilled parts sorted by index.
+func listSpilledParts(dir string) (parts []*types.Part, err error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fi := range fis {
+		idx := strings.Index(fi.Name(), ".")
+		if idx < 0 {
+			continue
+		}
+		// Skip meta file and parts which are still being spilled.
+		index, err := strconv.Atoi(fi.Name()[:idx])
+		if err != nil {
+			continue
+		}
+
+		parts = append(parts, &types.Part{
+			Index: index,
+			Size:  fi.Size(),
+			ETag:  fi.Name()[idx+1:],
+		})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Index < parts[j].Index
+	})
+	return parts, nil
+}
+
+// partReader reads the spilled parts in order as a whole.
+type partReader struct {
+	dir   string
+	parts []*types.Part
+	// offset is where to start reading in the first part.
+	offset int64
+
+	f *os.File
+}
+
+// newPartReader will create a reader over the parts starting from offset.
+func newPartReader(dir string, parts []*types.Part, offset int64) *partReader {
+	for len(parts) > 0 && offset >= parts[0].Size {
+		offset -= parts[0].Size
+		parts = parts[1:]
+	}
+
+	return &partReader{
+		dir:    dir,
+		parts:  parts,
+		offset: offset,
+	}
+}
+
+func (r *partReader) Read(p []byte) (n int, err error) {
+	for {
+		if r.f == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+
+			r.f, err = os.Open(getPartPath(r.dir, r.parts[0]))
+			if err != nil {
+				return 0, err
+			}
+			if r.offset > 0 {
+				_, err = r.f.Seek(r.offset, io.SeekStart)
+				if err != nil {
+					return 0, err
+				}
+				r.offset = 0
+			}
+		}
+
+		n, err = r.f.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+
+		// Move on to the next part.
+		err = r.f.Close()
+		r.f = nil
+		r.parts = r.parts[1:]
+		if err != nil || n > 0 {
+			return n, err
+		}
+	}
+}
+
+func (r *partReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
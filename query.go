@@ -0,0 +1,52 @@
+package gdrive
+
+import (
+	"strings"
+)
+
+// queryValueReplacer escapes a value in query, backslash must be escaped before single quote.
+// Ref: https://developers.google.com/drive/api/v3/ref-search-terms
+var queryValueReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// query builds the search query of files list and drives list, all terms will be combined with `and`.
+// Ref: https://developers.google.com/drive/api/v3/search-files
+type query struct {
+	terms []string
+}
+
+func newQuery() *query {
+	return &query{}
+}
+
+// newFileQuery returns a query of files list, trashed files are excluded, or they will be
+// found as duplicates of the living ones.
+func newFileQuery() *query {
+	return newQuery().raw("trashed = false")
+}
+
+// raw adds a term as is, the values in it must have been quoted by quoteQueryValue.
+func (q *query) raw(term string) *query {
+	q.terms = append(q.terms, term)
+	return q
+}
+
+// equal adds a term like `name = 'value'`.
+func (q *query) equal(field, value string) *query {
+	q.terms = append(q.terms, field+" = "+quoteQueryValue(value))
+	return q
+}
+
+// in adds a term like `'value' in parents`.
+func (q *query) in(value, field string) *query {
+	q.terms = append(q.terms, quoteQueryValue(value)+" in "+field)
+	return q
+}
+
+func (q *query) String() string {
+	return strings.Join(q.terms, " and ")
+}
+
+// quoteQueryValue will escape the value and quote it with single quotes.
+func quoteQueryValue(v string) string {
+	return "'" + queryValueReplacer.Replace(v) + "'"
+}
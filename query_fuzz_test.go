@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package gdrive
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzQuoteQueryValue(f *testing.F) {
+	for _, v := range quoteQueryValueSeeds {
+		f.Add(v)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		if !utf8.ValidString(name) {
+			t.Skip()
+		}
+		if err := checkQuoteQueryValue(name); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
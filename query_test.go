@@ -0,0 +1,112 @@
+package gdrive
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// unquoteQueryValue parses a quoted value in query, it's the reverse of quoteQueryValue.
+func unquoteQueryValue(s string) (string, error) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return "", errors.New("value is not quoted")
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i >= len(s) || (s[i] != '\\' && s[i] != '\'') {
+				return "", errors.New("invalid escape")
+			}
+		case '\'':
+			return "", errors.New("unescaped quote")
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), nil
+}
+
+func TestQuoteQueryValue(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"empty", "", `''`},
+		{"plain", "abc.txt", `'abc.txt'`},
+		{"quote", "O'Brien.pdf", `'O\'Brien.pdf'`},
+		{"backslash", `a\b`, `'a\\b'`},
+		{"backslash before quote", `a\'b`, `'a\\\'b'`},
+		{"unicode", "文件 名", `'文件 名'`},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteQueryValue(tt.input); got != tt.expect {
+				t.Errorf("quoteQueryValue(%q) = %q, expect %q", tt.input, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	q := newQuery().equal("name", "O'Brien.pdf").in("root", "parents").String()
+	expect := `name = 'O\'Brien.pdf' and 'root' in parents`
+	if q != expect {
+		t.Errorf("query = %q, expect %q", q, expect)
+	}
+}
+
+// quoteQueryValueSeeds are the values likely to break the quoting.
+var quoteQueryValueSeeds = []string{"", "abc", "O'Brien.pdf", `a\b`, `\'`, "'''", `\\\`, "文件"}
+
+// checkQuoteQueryValue checks whether the quoted name could be parsed back, and won't break
+// out of the term.
+func checkQuoteQueryValue(name string) error {
+	quoted := quoteQueryValue(name)
+	got, err := unquoteQueryValue(quoted)
+	if err != nil {
+		return fmt.Errorf("unquote %q: %w", quoted, err)
+	}
+	if got != name {
+		return fmt.Errorf("unquote %q = %q, expect %q", quoted, got, name)
+	}
+
+	term := newQuery().equal("name", name).String()
+	if !strings.HasPrefix(term, "name = ") || term[len("name = "):] != quoted {
+		return fmt.Errorf("term %q is broken by %q", term, name)
+	}
+	return nil
+}
+
+func TestQuoteQueryValueRoundTrip(t *testing.T) {
+	for _, v := range quoteQueryValueSeeds {
+		if err := checkQuoteQueryValue(v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	err := quick.Check(func(name string) bool {
+		if err := checkQuoteQueryValue(name); err != nil {
+			t.Log(err)
+			return false
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileQuery(t *testing.T) {
+	q := newFileQuery().in("root", "parents").String()
+	expect := `trashed = false and 'root' in parents`
+	if q != expect {
+		t.Errorf("query = %q, expect %q", q, expect)
+	}
+}
@@ -0,0 +1,137 @@
+package gdrive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate events per second, with bursts of at most burst events.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, math.Ceil(rate))
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// Take the token in advance, the callers will be served in order.
+	b.tokens--
+	tokens := b.tokens
+	b.mu.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-tokens / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// Give the token back as it's not used.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiter limits metadata requests and media transfers separately, nil bucket means unlimited.
+type rateLimiter struct {
+	metadata *tokenBucket
+	media    *tokenBucket
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	// rateLimiters are shared by all the clients of the same user, as gdrive enforces
+	// quotas per user.
+	rateLimiters = make(map[string]*rateLimiter)
+)
+
+// getRateLimiterKey returns the key of the user's limiter. Users authorized by the same OAuth2
+// client are separated by their tokens, and the key is hashed so that no secret is kept in it.
+func getRateLimiterKey(cred string, ao authOptions) string {
+	sum := sha256.Sum256([]byte(cred + "\x00" + ao.getUserKey()))
+	return hex.EncodeToString(sum[:])
+}
+
+// getRateLimiter returns the limiter shared by the key, it will be created with the rates if
+// not exists. Rates which are not positive mean unlimited.
+func getRateLimiter(key string, metadataRate, mediaRate float64) *rateLimiter {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	l, ok := rateLimiters[key]
+	if !ok {
+		l = &rateLimiter{}
+		rateLimiters[key] = l
+	}
+	// The first rate of every kind wins, so that all clients share the same budget.
+	if l.metadata == nil && metadataRate > 0 {
+		l.metadata = newTokenBucket(metadataRate)
+	}
+	if l.media == nil && mediaRate > 0 {
+		l.media = newTokenBucket(mediaRate)
+	}
+	return l
+}
+
+// rateLimitTransport waits for the rate limiter before every request.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func newRateLimitTransport(base http.RoundTripper, limiter *rateLimiter) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{base: base, limiter: limiter}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.limiter.metadata
+	if isMediaRequest(req) {
+		b = t.limiter.media
+	}
+	if b != nil {
+		err := b.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// isMediaRequest checks whether the request transfers the content of files, including
+// uploading, downloading and exporting.
+func isMediaRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/upload/") ||
+		strings.HasSuffix(req.URL.Path, "/export") ||
+		req.URL.Query().Get("alt") == "media"
+}
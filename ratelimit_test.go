@@ -0,0 +1,101 @@
+package gdrive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(20)
+
+	start := time.Now()
+	// The first 20 tokens are the burst, the following 10 tokens need 0.5s.
+	for i := 0; i < 30; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	if d := time.Since(start); d < 400*time.Millisecond || d > time.Second {
+		t.Errorf("30 tokens took %v, expect about 500ms", d)
+	}
+}
+
+func TestTokenBucketCanceled(t *testing.T) {
+	b := newTokenBucket(1)
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait got %v, expect deadline exceeded", err)
+	}
+}
+
+func TestGetRateLimiterShared(t *testing.T) {
+	a := getRateLimiter("base64:shared", 10, 0)
+	b := getRateLimiter("base64:shared", 100, 5)
+	other := getRateLimiter("base64:other", 100, 0)
+
+	if a != b {
+		t.Errorf("limiters with the same credential should be shared")
+	}
+	if a.metadata.rate != 10 || a.media == nil || a.media.rate != 5 {
+		t.Errorf("the first rate of every kind should win")
+	}
+	if a == other {
+		t.Errorf("limiters with different credentials should not be shared")
+	}
+}
+
+func TestGetRateLimiterKey(t *testing.T) {
+	cred := "hmac:id:secret"
+	alice := getRateLimiterKey(cred, authOptions{refreshToken: "alice"})
+
+	if alice != getRateLimiterKey(cred, authOptions{refreshToken: "alice"}) {
+		t.Errorf("keys of the same user should be the same")
+	}
+	if alice == getRateLimiterKey(cred, authOptions{refreshToken: "bob"}) {
+		t.Errorf("keys of users authorized by the same client should be different")
+	}
+	if alice == getRateLimiterKey(cred, authOptions{refreshToken: "alice", tokenFile: "alice.json"}) {
+		t.Errorf("users with token file should be identified by the file")
+	}
+
+	store := NewFileTokenStore("alice.json")
+	if getRateLimiterKey(cred, authOptions{tokenStore: store}) != getRateLimiterKey(cred, authOptions{tokenStore: store}) {
+		t.Errorf("keys of the same token store should be the same")
+	}
+	if getRateLimiterKey(cred, authOptions{tokenStore: store}) == getRateLimiterKey(cred, authOptions{tokenStore: NewFileTokenStore("bob.json")}) {
+		t.Errorf("keys of different token stores should be different")
+	}
+
+	if strings.Contains(alice, "secret") || strings.Contains(alice, "alice") {
+		t.Errorf("key %s should not contain secrets", alice)
+	}
+}
+
+func TestIsMediaRequest(t *testing.T) {
+	cases := []struct {
+		url    string
+		expect bool
+	}{
+		{"https://www.googleapis.com/drive/v3/files?q=name", false},
+		{"https://www.googleapis.com/drive/v3/files/abc?fields=id", false},
+		{"https://www.googleapis.com/drive/v3/files/abc?alt=media", true},
+		{"https://www.googleapis.com/drive/v3/files/abc/export?mimeType=application%2Fpdf", true},
+		{"https://www.googleapis.com/upload/drive/v3/files?uploadType=resumable", true},
+	}
+
+	for _, tt := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		if got := isMediaRequest(req); got != tt.expect {
+			t.Errorf("isMediaRequest(%s) = %v, expect %v", tt.url, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package gdrive
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/beyondstorage/go-storage/v4/services"
+)
+
+const (
+	defaultRetryMaxAttempts = 5
+	defaultRetryBaseDelay   = time.Second
+	defaultRetryMaxDelay    = 32 * time.Second
+	defaultRetryJitter      = 0.5
+
+	// retryErrorBodyLimit is the max size of error body to read for the reason of a 403.
+	retryErrorBodyLimit = 64 << 10
+)
+
+// RetryAttempt describes a failed attempt of a request.
+type RetryAttempt struct {
+	Method string
+	URL    string
+	// Attempt is the count of attempts made so far, starting from 1.
+	Attempt int
+	// StatusCode is the status code of the response, 0 if no response received.
+	StatusCode int
+	// Err is the error returned by the underlying transport.
+	Err error
+	// Retry is whether the request will be retried.
+	Retry bool
+	// Delay is how long to wait before the next attempt.
+	Delay time.Duration
+}
+
+// retryOptions controls how requests will be retried by retryTransport.
+type retryOptions struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	// jitter is the fraction of delay to be randomized, in [0, 1].
+	jitter float64
+	hook   func(RetryAttempt)
+}
+
+func newRetryOptions() retryOptions {
+	return retryOptions{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+		maxDelay:    defaultRetryMaxDelay,
+		jitter:      defaultRetryJitter,
+	}
+}
+
+// validate checks the options, the pair of invalid option will be returned as PairUnsupportedError.
+func (ro retryOptions) validate() error {
+	switch {
+	case ro.maxAttempts < 1:
+		return services.PairUnsupportedError{Pair: WithRetryMaxAttempts(ro.maxAttempts)}
+	case ro.baseDelay <= 0:
+		return services.PairUnsupportedError{Pair: WithRetryBaseDelay(ro.baseDelay)}
+	case ro.maxDelay < ro.baseDelay:
+		return services.PairUnsupportedError{Pair: WithRetryMaxDelay(ro.maxDelay)}
+	case ro.jitter < 0 || ro.jitter > 1:
+		return services.PairUnsupportedError{Pair: WithRetryJitter(ro.jitter)}
+	default:
+		return nil
+	}
+}
+
+// retryTransport retries throttled and failed requests with exponential backoff.
+//
+// Only idempotent requests whose body could be rewound will be retried. Requests to upload
+// sessions are skipped as they are retried by resumableUpload, which knows where to resume.
+type retryTransport struct {
+	base http.RoundTripper
+	ro   retryOptions
+}
+
+func newRetryTransport(base http.RoundTripper, ro retryOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, ro: ro}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	retryable := isRetryableRequest(req)
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		res, err = t.base.RoundTrip(req)
+		if !retryable || !t.shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		a := RetryAttempt{
+			Method:  req.Method,
+			URL:     req.URL.Redacted(),
+			Attempt: attempt,
+			Err:     err,
+			Retry:   attempt < t.ro.maxAttempts,
+		}
+		if res != nil {
+			a.StatusCode = res.StatusCode
+		}
+		if a.Retry {
+			a.Delay = t.getDelay(attempt, res)
+		}
+		if t.ro.hook != nil {
+			t.ro.hook(a)
+		}
+		if !a.Retry {
+			return res, err
+		}
+
+		// Drain the body so that the connection could be reused.
+		if res != nil {
+			googleapi.CloseBody(res)
+		}
+
+		timer := time.NewTimer(a.Delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableRequest checks whether the request could be sent again without side effects.
+func isRetryableRequest(req *http.Request) bool {
+	// The body is consumed by the first attempt, and could not be sent again.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	q := req.URL.Query()
+	if q.Get("upload_id") != "" {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodPatch:
+		return true
+	case http.MethodPost:
+		// Starting a resumable upload session creates nothing until the content uploaded, and
+		// creating a shared drive with the same requestId will not create another one.
+		return q.Get("uploadType") == "resumable" || q.Get("requestId") != ""
+	default:
+		return false
+	}
+}
+
+// shouldRetry checks whether the attempt failed by a throttled or transient error.
+func (t *retryTransport) shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if err != nil {
+		// Don't retry if the request is canceled by the caller.
+		return req.Context().Err() == nil && isRetryableError(err)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		return true
+	case res.StatusCode == http.StatusForbidden:
+		// gdrive returns 403 for both rate limit and permission denied, the reason in body tells.
+		content, rerr := ioutil.ReadAll(io.LimitReader(res.Body, retryErrorBodyLimit))
+		// Put the content back, so that the caller could read the whole body.
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(content), res.Body), res.Body}
+		if rerr != nil {
+			return false
+		}
+
+		cerr := googleapi.CheckResponse(&http.Response{
+			StatusCode: res.StatusCode,
+			Header:     res.Header,
+			Body:       ioutil.NopCloser(bytes.NewReader(content)),
+		})
+		return isRateLimited(cerr)
+	default:
+		return false
+	}
+}
+
+// getDelay calculates the delay before the next attempt, Retry-After will be honored if present.
+func (t *retryTransport) getDelay(attempt int, res *http.Response) time.Duration {
+	delay := t.ro.baseDelay
+	for i := 1; i < attempt && delay < t.ro.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.ro.maxDelay {
+		delay = t.ro.maxDelay
+	}
+	if t.ro.jitter > 0 {
+		delay -= time.Duration(t.ro.jitter * rand.Float64() * float64(delay))
+	}
+
+	if res != nil {
+		if after, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok && after > delay {
+			delay = after
+		}
+	}
+	return delay
+}
+
+// parseRetryAfter parses Retry-After which could be seconds or a http date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
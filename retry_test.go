@@ -0,0 +1,247 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const rateLimitBody = `{"error":{"code":403,"message":"User Rate Limit Exceeded","errors":[{"reason":"userRateLimitExceeded"}]}}`
+
+// newTestRetryClient returns a client retrying fast, and the attempts reported by hook.
+func newTestRetryClient(maxAttempts int) (*http.Client, *[]RetryAttempt) {
+	attempts := &[]RetryAttempt{}
+	ro := newRetryOptions()
+	ro.maxAttempts = maxAttempts
+	ro.baseDelay = time.Millisecond
+	ro.maxDelay = 10 * time.Millisecond
+	ro.hook = func(a RetryAttempt) {
+		*attempts = append(*attempts, a)
+	}
+	return &http.Client{Transport: newRetryTransport(nil, ro)}, attempts
+}
+
+// newFlakyServer returns a server which fails the first `failures` requests by fail.
+func newFlakyServer(failures int32, fail func(w http.ResponseWriter)) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1) <= failures {
+			fail(w)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	return srv, &count
+}
+
+func TestRetryTransportServerError(t *testing.T) {
+	srv, count := newFlakyServer(2, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer srv.Close()
+
+	client, attempts := newTestRetryClient(5)
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || *count != 3 {
+		t.Errorf("status %d after %d requests, expect 200 after 3", res.StatusCode, *count)
+	}
+	if len(*attempts) != 2 || (*attempts)[1].Attempt != 2 || !(*attempts)[1].Retry {
+		t.Errorf("hook got %+v, expect 2 retried attempts", *attempts)
+	}
+}
+
+func TestRetryTransportRateLimit(t *testing.T) {
+	srv, count := newFlakyServer(1, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(rateLimitBody))
+	})
+	defer srv.Close()
+
+	client, _ := newTestRetryClient(5)
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader("content"))
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	// The body should be sent again.
+	if res.StatusCode != http.StatusOK || string(body) != "content" || *count != 2 {
+		t.Errorf("status %d, body %q after %d requests", res.StatusCode, body, *count)
+	}
+}
+
+func TestRetryTransportPermissionDenied(t *testing.T) {
+	permissionBody := `{"error":{"code":403,"errors":[{"reason":"insufficientFilePermissions"}]}}`
+	srv, count := newFlakyServer(1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(permissionBody))
+	})
+	defer srv.Close()
+
+	client, _ := newTestRetryClient(5)
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusForbidden || *count != 1 {
+		t.Errorf("status %d after %d requests, expect 403 after 1", res.StatusCode, *count)
+	}
+	// The body read for the reason should be kept for the caller.
+	if string(body) != permissionBody {
+		t.Errorf("body %q, expect %q", body, permissionBody)
+	}
+}
+
+func TestRetryTransportGiveUp(t *testing.T) {
+	srv, count := newFlakyServer(10, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer srv.Close()
+
+	client, attempts := newTestRetryClient(3)
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadGateway || *count != 3 {
+		t.Errorf("status %d after %d requests, expect 502 after 3", res.StatusCode, *count)
+	}
+	if len(*attempts) != 3 || (*attempts)[2].Retry {
+		t.Errorf("hook got %+v, expect the last attempt not retried", *attempts)
+	}
+}
+
+func TestRetryTransportNonIdempotent(t *testing.T) {
+	srv, count := newFlakyServer(1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer srv.Close()
+
+	client, _ := newTestRetryClient(5)
+	// Creating a file is not idempotent.
+	res, err := client.Post(srv.URL+"/drive/v3/files", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || *count != 1 {
+		t.Errorf("status %d after %d requests, expect 503 after 1", res.StatusCode, *count)
+	}
+
+	// Starting a resumable upload session is safe to retry.
+	res, err = client.Post(srv.URL+"/upload/drive/v3/files?uploadType=resumable", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status %d, expect 200", res.StatusCode)
+	}
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	srv, _ := newFlakyServer(1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer srv.Close()
+
+	client, attempts := newTestRetryClient(5)
+	start := time.Now()
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	res.Body.Close()
+
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("retried after %v, expect Retry-After honored", d)
+	}
+	if len(*attempts) != 1 || (*attempts)[0].Delay != time.Second {
+		t.Errorf("hook got %+v, expect delay 1s", *attempts)
+	}
+}
+
+func TestRetryTransportCanceled(t *testing.T) {
+	srv, _ := newFlakyServer(10, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer srv.Close()
+
+	client, _ := newTestRetryClient(5)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("get got %v, expect deadline exceeded", err)
+	}
+}
+
+func TestGetRetryDelay(t *testing.T) {
+	ro := newRetryOptions()
+	ro.jitter = 0
+	tr := &retryTransport{ro: ro}
+
+	expects := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, 32 * time.Second}
+	for i, expect := range expects {
+		if d := tr.getDelay(i+1, nil); d != expect {
+			t.Errorf("delay of attempt %d = %v, expect %v", i+1, d, expect)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{"server error", newGoogleAPIError(http.StatusServiceUnavailable, "backendError"), true},
+		{"rate limit", newGoogleAPIError(http.StatusForbidden, "userRateLimitExceeded"), true},
+		{"permission", newGoogleAPIError(http.StatusForbidden, "insufficientFilePermissions"), false},
+		{"connection", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+		{"client", &url.Error{Op: "Put", URL: "https://example.com", Err: io.EOF}, true},
+		{"connection closed", io.EOF, true},
+		{"truncated body", fmt.Errorf("decode: %w", io.ErrUnexpectedEOF), true},
+		{"malformed range", fmt.Errorf("parse range %q: %w", "bytes=0-x", strconv.ErrSyntax), false},
+		{"malformed body", &json.SyntaxError{Offset: 1}, false},
+		{"canceled", context.Canceled, false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.expect {
+				t.Errorf("isRetryableError(%v) = %v, expect %v", tt.err, got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package gdrive
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/api/drive/v3"
+
+	"github.com/beyondstorage/go-storage/v4/types"
+)
+
+func (s *Service) create(ctx context.Context, name string, opt pairServiceCreate) (store types.Storager, err error) {
+	// requestId makes the creation idempotent, a retried request will not create another drive.
+	// Ref: https://developers.google.com/drive/api/v3/reference/drives/create
+	d, err := s.service.Drives.Create(uuid.New().String(), &drive.Drive{Name: name}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return s.newStorage(d.Id, d.Name)
+}
+
+func (s *Service) delete(ctx context.Context, name string, opt pairServiceDelete) (err error) {
+	driveId, err := s.getDriveId(ctx, name)
+	if err != nil {
+		return err
+	}
+	// Only an empty shared drive could be deleted.
+	return s.service.Drives.Delete(driveId).Context(ctx).Do()
+}
+
+func (s *Service) get(ctx context.Context, name string, opt pairServiceGet) (store types.Storager, err error) {
+	driveId, err := s.getDriveId(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.newStorage(driveId, name)
+}
+
+// getDriveId will get the id of the shared drive by it's name.
+// The names of shared drives are not unique, the first one will be used.
+func (s *Service) getDriveId(ctx context.Context, name string) (string, error) {
+	r, err := s.service.Drives.List().
+		Q(newQuery().equal("name", name).String()).
+		PageSize(1).
+		Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if len(r.Drives) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrDriveNotExist, name)
+	}
+	return r.Drives[0].Id, nil
+}
+
+func (s *Service) list(ctx context.Context, opt pairServiceList) (it *types.StoragerIterator, err error) {
+	input := &storagePageStatus{}
+
+	return types.NewStoragerIterator(ctx, s.nextStoragePage, input), nil
+}
+
+func (s *Service) nextStoragePage(ctx context.Context, page *types.StoragerPage) error {
+	input := page.Status.(*storagePageStatus)
+
+	call := s.service.Drives.List().PageSize(100).Context(ctx)
+	if input.pageToken != "" {
+		call = call.PageToken(input.pageToken)
+	}
+	r, err := call.Do()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range r.Drives {
+		store, err := s.newStorage(v.Id, v.Name)
+		if err != nil {
+			return err
+		}
+		page.Data = append(page.Data, store)
+	}
+
+	input.pageToken = r.NextPageToken
+	if input.pageToken == "" {
+		return types.IterateDone
+	}
+	return nil
+}
@@ -2,19 +2,185 @@ package gdrive
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
 	"github.com/beyondstorage/go-storage/v4/pkg/iowrap"
 	"github.com/beyondstorage/go-storage/v4/services"
 	. "github.com/beyondstorage/go-storage/v4/types"
 )
 
-const directoryMimeType = "application/vnd.google-apps.folder"
+const (
+	directoryMimeType = "application/vnd.google-apps.folder"
+	// workspaceMimeTypePrefix is the prefix of all Google Workspace mime types.
+	workspaceMimeTypePrefix = "application/vnd.google-apps."
+
+	// copyConcurrency is the max number of files copied at the same time while copying a directory.
+	copyConcurrency = 8
+)
+
+// defaultExportMimeTypes is the mime types Google Workspace documents will be exported into by default.
+var defaultExportMimeTypes = map[string]string{
+	"application/vnd.google-apps.document":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.google-apps.spreadsheet":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.google-apps.presentation": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.google-apps.drawing":      "application/pdf",
+}
+
+// checkContentMd5 will compare the md5 checksum returned by gdrive with the expected content md5.
+// The broken content will be removed if they are mismatched, so that it won't be read: a created file
+// will be trashed, and for an updated file only it's head revision will be deleted, so that the
+// previous content is kept.
+func (s *Storage) checkContentMd5(ctx context.Context, path string, f *drive.File, created bool, expected string) (err error) {
+	// gdrive doesn't return md5 checksum for some files, e.g. Google Workspace documents, which
+	// could not be verified, and should not be removed as mismatched.
+	if f.Md5Checksum == "" {
+		return nil
+	}
+
+	sum, err := hex.DecodeString(f.Md5Checksum)
+	if err != nil {
+		return err
+	}
+
+	actual := base64.StdEncoding.EncodeToString(sum)
+	if actual == expected {
+		return nil
+	}
+
+	if created {
+		_, err = s.service.Files.Update(f.Id, &drive.File{Trashed: true}).SupportsAllDrives(true).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		s.delCache(s.getAbsPath(path))
+	} else {
+		err = s.service.Revisions.Delete(f.Id, f.HeadRevisionId).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+	}
+
+	return ContentMd5MismatchError{Expected: expected, Actual: actual}
+}
+
+// checkReplaceable makes sure dst could be replaced by src. A directory dst is refused, as it
+// will be deleted with all of it's contents, which could include src.
+func (s *Storage) checkReplaceable(ctx context.Context, src, dst, dstFileId string) error {
+	if isSubPath(s.getAbsPath(dst), s.getAbsPath(src)) {
+		return fmt.Errorf("%w: %s is under %s", services.ErrObjectModeInvalid, src, dst)
+	}
+	if dstFileId == "" {
+		return nil
+	}
+
+	f, err := s.service.Files.Get(dstFileId).SupportsAllDrives(true).Context(ctx).Fields("mimeType").Do()
+	if err != nil {
+		return err
+	}
+	if f.MimeType == directoryMimeType {
+		return fmt.Errorf("%w: %s is a directory", services.ErrObjectModeInvalid, dst)
+	}
+	return nil
+}
+
+// commitAppend will upload the spooled content as a whole, so that readers will see either
+// the previous content or the full appended one.
+func (s *Storage) commitAppend(ctx context.Context, o *Object, opt pairStorageCommitAppend) (err error) {
+	spool := s.getAppendPath(o.ID)
+
+	f, err := os.Open(spool)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return services.ErrObjectNotExist
+		}
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	_, err = s.write(ctx, o.Path, f, fi.Size(), pairStorageWrite{})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(spool)
+}
+
+func (s *Storage) completeMultipart(ctx context.Context, o *Object, parts []*Part, opt pairStorageCompleteMultipart) (err error) {
+	multipartID := o.MustGetMultipartID()
+	dir := s.getMultipartDir(multipartID)
+
+	meta, err := readMultipartMeta(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return services.ErrObjectNotExist
+		}
+		return err
+	}
+
+	spilled, err := listSpilledParts(dir)
+	if err != nil {
+		return err
+	}
+	spilledMap := make(map[int]*Part, len(spilled))
+	for _, p := range spilled {
+		spilledMap[p.Index] = p
+	}
+
+	var size int64
+	ordered := make([]*Part, 0, len(parts))
+	for i, p := range parts {
+		// Content can only be appended into an upload session in sequence, so parts must be sorted by index.
+		if i > 0 && p.Index <= parts[i-1].Index {
+			return fmt.Errorf("%w: part %d is out of order", ErrPartInvalid, p.Index)
+		}
+
+		sp, ok := spilledMap[p.Index]
+		if !ok || sp.Size != p.Size || (p.ETag != "" && p.ETag != sp.ETag) {
+			return fmt.Errorf("%w: part %d doesn't match the written one", ErrPartInvalid, p.Index)
+		}
+
+		ordered = append(ordered, sp)
+		size += sp.Size
+	}
+
+	// The upload could be completed partially before restart, so we continue from where gdrive has persisted.
+	offset, f, err := s.queryUploadSession(ctx, multipartID, size)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		r := newPartReader(dir, ordered, offset)
+		defer r.Close()
+
+		f, err = s.resumableUpload(ctx, multipartID, r, offset, size, uploadOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.setCache(meta.Path, f.Id)
+	return os.RemoveAll(dir)
+}
 
 func (s *Storage) copy(ctx context.Context, src string, dst string, opt pairStorageCopy) (err error) {
 
@@ -24,27 +190,161 @@ func (s *Storage) copy(ctx context.Context, src string, dst string, opt pairStor
 	if err != nil {
 		return err
 	}
+	if srcFileId == "" {
+		return services.ErrObjectNotExist
+	}
+
+	srcFile, err := s.service.Files.Get(srcFileId).SupportsAllDrives(true).Context(ctx).Fields("mimeType").Do()
+	if err != nil {
+		return err
+	}
+	// The walk would find the dst under src again and again if we copy a directory into itself.
+	if srcFile.MimeType == directoryMimeType && isSubPath(s.getAbsPath(src), s.getAbsPath(dst)) {
+		return fmt.Errorf("%w: %s into %s", ErrCopyIntoSelf, src, dst)
+	}
 
 	dstFileId, err := s.pathToId(ctx, dst)
 	if err != nil {
 		return err
 	}
+	// src and dst are the same file, removing dst will lose it.
+	if dstFileId == srcFileId {
+		return nil
+	}
+	err = s.checkReplaceable(ctx, src, dst, dstFileId)
+	if err != nil {
+		return err
+	}
 
 	// FIXME: I don't know how to directly copy a file into an existing one
 	if dstFileId != "" {
-		err = s.service.Files.Delete(dstFileId).Do()
+		err = s.service.Files.Delete(dstFileId).SupportsAllDrives(true).Context(ctx).Do()
+		// The delete could be retried after the response lost, dst has been deleted then.
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		s.delCacheTree(s.getAbsPath(dst))
+	}
+
+	dirs, fileName := filepath.Split(s.getAbsPath(dst))
+	parentsId := s.getWorkDirId()
+	if dirs != "" {
+		parentsId, err = s.createDirs(ctx, dirs)
+		if err != nil {
+			return err
+		}
+	}
+
+	// gdrive refuses to copy a folder, so we have to rebuild the tree by ourselves.
+	if srcFile.MimeType == directoryMimeType {
+		dstDirId, err := s.mkDir(ctx, parentsId, fileName)
 		if err != nil {
 			return err
 		}
+		s.setCache(s.getAbsPath(dst), dstDirId)
+
+		return s.copyDir(ctx, src, srcFileId, s.getAbsPath(dst), dstDirId)
 	}
+
 	dstFile = &drive.File{
-		Name: s.getFileName(dst),
+		Name:    fileName,
+		Parents: []string{parentsId},
 	}
-	_, err = s.service.Files.Copy(srcFileId, dstFile).Context(ctx).Do()
+	f, err := s.service.Files.Copy(srcFileId, dstFile).SupportsAllDrives(true).Context(ctx).Do()
 	if err != nil {
 		return err
 	}
 
+	s.setCache(s.getAbsPath(dst), f.Id)
+	return nil
+}
+
+// copyDir will walk the src directory breadth-first, recreate every sub directory under
+// dstDirId and copy files concurrently.
+// Failures will not stop the walk, they will be collected and returned as a CopyPartialError.
+func (s *Storage) copyDir(ctx context.Context, src, srcDirId, dstAbsPath, dstDirId string) error {
+	type dirEntry struct {
+		srcId string
+		dstId string
+		rel   string
+	}
+	type fileEntry struct {
+		srcId     string
+		parentsId string
+		name      string
+		rel       string
+	}
+
+	src = strings.TrimSuffix(src, "/")
+
+	failed := make(map[string]error)
+	var files []fileEntry
+
+	dirs := []dirEntry{{srcId: srcDirId, dstId: dstDirId}}
+	for len(dirs) > 0 {
+		d := dirs[0]
+		dirs = dirs[1:]
+
+		contents, err := s.listContentInDir(ctx, d.srcId)
+		if err != nil {
+			failed[src+d.rel] = err
+			continue
+		}
+
+		for _, f := range contents {
+			rel := d.rel + "/" + f.Name
+
+			if f.MimeType != directoryMimeType {
+				files = append(files, fileEntry{srcId: f.Id, parentsId: d.dstId, name: f.Name, rel: rel})
+				continue
+			}
+
+			id, err := s.mkDir(ctx, d.dstId, f.Name)
+			if err != nil {
+				failed[src+rel] = err
+				continue
+			}
+			s.setCache(dstAbsPath+rel, id)
+			dirs = append(dirs, dirEntry{srcId: f.Id, dstId: id, rel: rel})
+		}
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, copyConcurrency)
+	)
+	for _, f := range files {
+		f := f
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			dstFile := &drive.File{
+				Name:    f.name,
+				Parents: []string{f.parentsId},
+			}
+			nf, err := s.service.Files.Copy(f.srcId, dstFile).SupportsAllDrives(true).Context(ctx).Do()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[src+f.rel] = err
+				return
+			}
+			s.setCache(dstAbsPath+f.rel, nf.Id)
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return CopyPartialError{Failed: failed}
+	}
 	return nil
 }
 
@@ -52,16 +352,51 @@ func (s *Storage) create(path string, opt pairStorageCreate) (o *Object) {
 	o = s.newObject(false)
 	o.ID = s.getAbsPath(path)
 	o.Path = path
+
+	if opt.HasMultipartID {
+		o.Mode |= ModePart
+		o.SetMultipartID(opt.MultipartID)
+	}
 	return o
 }
 
+// createAppend will create an empty local spool for the object, appended content will be
+// accumulated in it until commit.
+func (s *Storage) createAppend(ctx context.Context, path string, opt pairStorageCreateAppend) (o *Object, err error) {
+	rp := s.getAbsPath(path)
+	spool := s.getAppendPath(rp)
+
+	err = os.MkdirAll(filepath.Dir(spool), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Truncate the spool if exists, so that we will always start from 0.
+	f, err := os.OpenFile(spool, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	o = s.newObject(true)
+	o.ID = rp
+	o.Path = path
+	o.Mode |= ModeAppend
+	o.SetAppendOffset(0)
+	return o, nil
+}
+
 func (s *Storage) createDir(ctx context.Context, path string, opt pairStorageCreateDir) (o *Object, err error) {
 
-	_, err = s.createDirs(ctx, path)
+	dirId, err := s.createDirs(ctx, path)
 
 	if err != nil {
 		return nil, err
 	}
+	s.setCache(s.getAbsPath(path), dirId)
 
 	o = s.newObject(true)
 	o.ID = s.getAbsPath(path)
@@ -75,8 +410,12 @@ func (s *Storage) createDir(ctx context.Context, path string, opt pairStorageCre
 // This function is very similar to `createDir` but has different uses. Unlike `creatDir`, it
 // is mainly responsible for communicating with gdrive API
 func (s *Storage) createDirs(ctx context.Context, path string) (parentsId string, err error) {
-	pathUnits := strings.Split(path, "/")
-	parentsId = "root"
+	pathUnits := strings.Split(s.getWalkPath(path), "/")
+	parentsId = s.getWorkDirId()
+	cacheCurrentPath := ""
+	if s.workDirId != "" {
+		cacheCurrentPath = strings.Trim(s.workDir, "/")
+	}
 
 	for _, v := range pathUnits {
 		// TODO: use `strings.Split` to split path is not perfect, maybe
@@ -86,44 +425,269 @@ func (s *Storage) createDirs(ctx context.Context, path string) (parentsId string
 			if err != nil {
 				return "", err
 			}
+
+			cacheCurrentPath += "/" + v
+			s.setCache(cacheCurrentPath, parentsId)
 		}
 	}
 
 	return parentsId, nil
 }
 
+// createMultipart will start a resumable upload session without size, and the session uri
+// will be used as the multipart id. Parts will be spilled into a local dir until complete.
+func (s *Storage) createMultipart(ctx context.Context, path string, opt pairStorageCreateMultipart) (o *Object, err error) {
+	fileId, err := s.pathToId(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &drive.File{}
+	if fileId == "" {
+		dirs, fileName := filepath.Split(s.getAbsPath(path))
+		parentsId := s.getWorkDirId()
+		if dirs != "" {
+			parentsId, err = s.createDirs(ctx, dirs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		file.Name = fileName
+		file.Parents = []string{parentsId}
+	}
+
+	multipartID, err := s.createUploadSession(ctx, fileId, file, "", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := s.getMultipartDir(multipartID)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	err = writeMultipartMeta(dir, multipartMeta{
+		Path:        s.getAbsPath(path),
+		MultipartID: multipartID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o = s.newObject(true)
+	o.ID = s.getAbsPath(path)
+	o.Path = path
+	o.Mode |= ModePart
+	o.SetMultipartID(multipartID)
+	return o, nil
+}
+
+// dedupDir checks whether the directory we just created is duplicated with the ones created
+// by others at the same time, e.g. other processes. All creators and lookups agree to keep the
+// oldest one, and the loser created by us will be trashed instead of deleted, so that contents
+// written into it before the duplicate is found could still be recovered.
+func (s *Storage) dedupDir(ctx context.Context, parents string, dirName string, dirId string) (string, error) {
+	files, err := s.listNameInDir(ctx, newFileQuery().equal("mimeType", directoryMimeType), parents, dirName)
+	if err != nil {
+		return "", err
+	}
+	if len(files) <= 1 {
+		return dirId, nil
+	}
+
+	winner, err := resolveDuplicate(DuplicatePolicyOldest, dirName, files)
+	if err != nil {
+		return "", err
+	}
+	if winner.Id == dirId {
+		return dirId, nil
+	}
+
+	_, err = s.service.Files.Update(dirId, &drive.File{Trashed: true}).SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil && !isNotFound(err) {
+		return "", err
+	}
+	return winner.Id, nil
+}
+
 func (s *Storage) delete(ctx context.Context, path string, opt pairStorageDelete) (err error) {
+	if opt.HasMultipartID {
+		err = s.cancelUploadSession(ctx, opt.MultipartID)
+		if err != nil {
+			return err
+		}
+		return os.RemoveAll(s.getMultipartDir(opt.MultipartID))
+	}
+
+	// Abort the unfinished append if exists.
+	err = os.Remove(s.getAppendPath(s.getAbsPath(path)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
 	var fileId string
 	fileId, err = s.pathToId(ctx, path)
 	if err != nil {
 		return err
 	}
-	err = s.service.Files.Delete(fileId).Do()
+	if fileId == "" {
+		return nil
+	}
+	err = s.service.Files.Delete(fileId).SupportsAllDrives(true).Context(ctx).Do()
 
 	// Omit `path_lookup/not_found` error here.
 	// ref: [GSP-46](https://github.com/beyondstorage/specs/blob/master/rfcs/46-idempotent-delete.md)
-	if err != nil && strings.Contains(err.Error(), "404") {
+	if err != nil && isNotFound(err) {
 		err = nil
 	}
 	if err != nil {
 		return err
 	}
+
+	// The path could be a directory, all of it's contents have gone with it.
+	s.delCacheTree(s.getAbsPath(path))
 	return nil
 }
 
+// downloadFile will download the binary content of a file, with offset and size applied by range.
+func (s *Storage) downloadFile(ctx context.Context, fileId string, opt pairStorageRead) (rc io.ReadCloser, err error) {
+	fileGetCall := s.service.Files.Get(fileId).SupportsAllDrives(true)
+	if opt.HasOffset && !opt.HasSize {
+		rangeBytes := fmt.Sprintf("bytes=%d-", opt.Offset)
+		fileGetCall.Header().Add("Range", rangeBytes)
+	} else if !opt.HasOffset && opt.HasSize {
+		rangeBytes := fmt.Sprintf("bytes=0-%d", opt.Size-1)
+		fileGetCall.Header().Add("Range", rangeBytes)
+	} else if opt.HasOffset && opt.HasSize {
+		rangeBytes := fmt.Sprintf("bytes=%d-%d", opt.Offset, opt.Offset+opt.Size-1)
+		fileGetCall.Header().Add("Range", rangeBytes)
+	}
+	f, err := fileGetCall.Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	return f.Body, nil
+}
+
+// exportFile will export a Google Workspace document into mimeType.
+// Export doesn't support range, so offset and size will be applied locally.
+func (s *Storage) exportFile(ctx context.Context, fileId string, mimeType string, opt pairStorageRead) (rc io.ReadCloser, err error) {
+	f, err := s.service.Files.Export(fileId, mimeType).Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	rc = f.Body
+
+	if opt.HasOffset {
+		_, err = io.CopyN(ioutil.Discard, rc, opt.Offset)
+		if err != nil && err != io.EOF {
+			rc.Close()
+			return nil, err
+		}
+	}
+	if opt.HasSize {
+		rc = iowrap.LimitReadCloser(rc, opt.Size)
+	}
+	return rc, nil
+}
+
+// getExportMimeType will get the mime type a Google Workspace document should be exported into.
+func (s *Storage) getExportMimeType(ctx context.Context, fileId string) (string, error) {
+	f, err := s.service.Files.Get(fileId).SupportsAllDrives(true).Context(ctx).Fields("mimeType").Do()
+	if err != nil {
+		return "", err
+	}
+
+	exportMimeType, ok := s.exportMimeTypes[f.MimeType]
+	if !ok {
+		return "", fmt.Errorf("%w: no export mime type for %s", ErrExportMimeTypeMissing, f.MimeType)
+	}
+	return exportMimeType, nil
+}
+
 func (s *Storage) list(ctx context.Context, path string, opt pairStorageList) (oi *ObjectIterator, err error) {
 	input := &objectPageStatus{
 		limit: 200,
-		path:  s.getAbsPath(path),
+		path:  path,
+	}
+
+	if opt.HasContinuationToken {
+		input.pageToken = opt.ContinuationToken
 	}
 
 	if !opt.HasListMode || opt.ListMode.IsDir() {
 		return NewObjectIterator(ctx, s.nextObjectPage, input), nil
+	} else if opt.ListMode.IsPart() {
+		return NewObjectIterator(ctx, s.nextPartObjectPage, input), nil
+	} else if opt.ListMode.IsPrefix() {
+		input.prefix = true
+
+		if opt.HasContinuationToken {
+			t, err := parsePrefixContinuationToken(opt.ContinuationToken)
+			if err != nil {
+				return nil, err
+			}
+			input.pageToken = t.PageToken
+			input.dirs = t.Dirs
+			return NewObjectIterator(ctx, s.nextObjectPageByPrefix, input), nil
+		}
+
+		// The last unit of path could be a part of the name, so we start walking from it's
+		// parent directory, and filter the direct contents with the name prefix.
+		dir, namePrefix := "", path
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			dir, namePrefix = path[:i], path[i+1:]
+		}
+
+		dirId, err := s.pathToId(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		// Nothing to list if the parent directory doesn't exist.
+		if dirId != "" {
+			input.dirs = []dirPageStatus{{Id: dirId, Path: s.getAbsPath(dir), NamePrefix: namePrefix}}
+		}
+		return NewObjectIterator(ctx, s.nextObjectPageByPrefix, input), nil
 	} else {
 		return nil, services.ListModeInvalidError{Actual: opt.ListMode}
 	}
 }
 
+// listContentInDir will list all the contents of a directory by passing it's fileId.
+func (s *Storage) listContentInDir(ctx context.Context, dirId string) (files []*drive.File, err error) {
+	q := s.newFilesListCall().Context(ctx).Q(newFileQuery().in(dirId, "parents").String()).Fields("*")
+
+	err = q.Pages(ctx, func(r *drive.FileList) error {
+		files = append(files, r.Files...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (s *Storage) listMultipart(ctx context.Context, o *Object, opt pairStorageListMultipart) (pi *PartIterator, err error) {
+	input := &partPageStatus{
+		dir: s.getMultipartDir(o.MustGetMultipartID()),
+	}
+
+	return NewPartIterator(ctx, s.nextPartPage, input), nil
+}
+
+// listNameInDir will list all the contents matching q with the name in the folder.
+func (s *Storage) listNameInDir(ctx context.Context, q *query, dirId string, name string) (files []*drive.File, err error) {
+	searchArg := q.equal("name", name).in(dirId, "parents").String()
+	fileList, err := s.newFilesListCall().Context(ctx).Q(searchArg).
+		PageSize(1000).
+		Fields("files(id,mimeType,createdTime,modifiedTime)").Do()
+	if err != nil {
+		return nil, err
+	}
+	return fileList.Files, nil
+}
+
 func (s *Storage) metadata(opt pairStorageMetadata) (meta *StorageMeta) {
 	meta = NewStorageMeta()
 	meta.Name = s.name
@@ -135,7 +699,12 @@ func (s *Storage) metadata(opt pairStorageMetadata) (meta *StorageMeta) {
 // It will return the fileId of the directory whether it exist or not.
 // If error occurs, it will return an empty string and error.
 func (s *Storage) mkDir(ctx context.Context, parents string, dirName string) (string, error) {
-	id, err := s.searchContentInDir(ctx, parents, dirName)
+	// Search-then-create is not atomic, the directory should be locked, or concurrent writes
+	// under the same directory will create duplicate ones.
+	unlock := s.dirLocks.lock(parents + "/" + dirName)
+	defer unlock()
+
+	id, err := s.searchDirInDir(ctx, parents, dirName)
 	if err != nil {
 		return "", err
 	}
@@ -150,11 +719,78 @@ func (s *Storage) mkDir(ctx context.Context, parents string, dirName string) (st
 		Parents:  []string{parents},
 		MimeType: directoryMimeType,
 	}
-	f, err := s.service.Files.Create(dir).Context(ctx).Do()
+	f, err := s.service.Files.Create(dir).SupportsAllDrives(true).Context(ctx).Do()
 	if err != nil {
 		return "", err
 	}
-	return f.Id, nil
+	return s.dedupDir(ctx, parents, dirName, f.Id)
+}
+
+// move will re-parent and rename the src file in place, so the fileId and content
+// are kept as is and no data needs to be uploaded again.
+func (s *Storage) move(ctx context.Context, src string, dst string, opt pairStorageMove) (err error) {
+	srcFileId, err := s.pathToId(ctx, src)
+	if err != nil {
+		return err
+	}
+	if srcFileId == "" {
+		return services.ErrObjectNotExist
+	}
+
+	srcFile, err := s.service.Files.Get(srcFileId).SupportsAllDrives(true).Context(ctx).Fields("mimeType", "parents").Do()
+	if err != nil {
+		return err
+	}
+	// dst will be created inside src if we move a directory into itself.
+	if srcFile.MimeType == directoryMimeType && isSubPath(s.getAbsPath(src), s.getAbsPath(dst)) {
+		return fmt.Errorf("%w: move %s into itself", services.ErrObjectModeInvalid, src)
+	}
+
+	dstFileId, err := s.pathToId(ctx, dst)
+	if err != nil {
+		return err
+	}
+	// src and dst are the same file, removing dst will lose it.
+	if dstFileId == srcFileId {
+		return nil
+	}
+	err = s.checkReplaceable(ctx, src, dst, dstFileId)
+	if err != nil {
+		return err
+	}
+
+	// Move should not return an error as dst object exists, so we remove it first.
+	if dstFileId != "" {
+		err = s.service.Files.Delete(dstFileId).SupportsAllDrives(true).Context(ctx).Do()
+		// The delete could be retried after the response lost, dst has been deleted then.
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		s.delCacheTree(s.getAbsPath(dst))
+	}
+
+	dirs, fileName := filepath.Split(s.getAbsPath(dst))
+	parentsId := s.getWorkDirId()
+	if dirs != "" {
+		parentsId, err = s.createDirs(ctx, dirs)
+		if err != nil {
+			return err
+		}
+	}
+
+	newFile := &drive.File{Name: fileName}
+	_, err = s.service.Files.Update(srcFileId, newFile).SupportsAllDrives(true).
+		AddParents(parentsId).
+		RemoveParents(strings.Join(srcFile.Parents, ",")).
+		Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	// Contents of a moved directory will be looked up again under dst.
+	s.delCacheTree(s.getAbsPath(src))
+	s.setCache(s.getAbsPath(dst), srcFileId)
+	return nil
 }
 
 func (s *Storage) nextObjectPage(ctx context.Context, page *ObjectPage) (err error) {
@@ -165,36 +801,145 @@ func (s *Storage) nextObjectPage(ctx context.Context, page *ObjectPage) (err err
 	if err != nil {
 		return err
 	}
-	q := s.service.Files.List().Q(fmt.Sprintf("parents='%s'", dirId)).Fields("*")
+	// Nothing to list if the directory doesn't exist.
+	if dirId == "" {
+		return IterateDone
+	}
+	dir := s.getAbsPath(input.path)
+	// Drive could return an empty page with a next page token, keep listing until we get
+	// something or there are no more pages.
+	for len(page.Data) == 0 {
+		q := s.newFilesListCall().Context(ctx).Q(newFileQuery().in(dirId, "parents").String()).Fields("*")
+		if input.pageToken != "" {
+			q = q.PageToken(input.pageToken)
+		}
+		r, err := q.Do()
+		if err != nil {
+			return err
+		}
+
+		for _, f := range r.Files {
+			path := dir + "/" + f.Name
+			// Seed the cache so that the following operations on listed objects don't need to
+			// walk the whole hierarchy again.
+			s.setCache(path, f.Id)
+
+			o, err := s.formatFileObject(f, path)
+			if err != nil {
+				return err
+			}
+			page.Data = append(page.Data, o)
+		}
+
+		input.pageToken = r.NextPageToken
+		if input.pageToken == "" {
+			return IterateDone
+		}
+	}
+	return nil
+}
+
+// nextObjectPageByPrefix will walk the directories breadth-first, every call lists one page
+// of the head directory in queue, and the sub directories will be pushed back into the queue.
+func (s *Storage) nextObjectPageByPrefix(ctx context.Context, page *ObjectPage) (err error) {
+	input := page.Status.(*objectPageStatus)
 
-	if input.pageToken != "" {
-		q = q.PageToken(input.pageToken)
+	// An empty page will stop the iterator, so we need to keep walking until we get something.
+	for len(page.Data) == 0 {
+		if len(input.dirs) == 0 {
+			return IterateDone
+		}
+		dir := input.dirs[0]
+
+		q := s.newFilesListCall().Context(ctx).
+			Q(newFileQuery().in(dir.Id, "parents").String()).
+			PageSize(int64(input.limit)).
+			Fields("*")
+		if input.pageToken != "" {
+			q = q.PageToken(input.pageToken)
+		}
+		r, err := q.Do()
+		if err != nil {
+			return err
+		}
+
+		for _, f := range r.Files {
+			if !strings.HasPrefix(f.Name, dir.NamePrefix) {
+				continue
+			}
+
+			path := dir.Path + "/" + f.Name
+			s.setCache(path, f.Id)
+
+			if f.MimeType == directoryMimeType {
+				input.dirs = append(input.dirs, dirPageStatus{Id: f.Id, Path: path})
+				continue
+			}
+
+			o, err := s.formatFileObject(f, path)
+			if err != nil {
+				return err
+			}
+			page.Data = append(page.Data, o)
+		}
+
+		input.pageToken = r.NextPageToken
+		// Move on to the next directory after the last page of current one.
+		if input.pageToken == "" {
+			input.dirs = input.dirs[1:]
+		}
 	}
-	r, err := q.Do()
 
-	if err != nil {
+	return nil
+}
+
+func (s *Storage) nextPartObjectPage(ctx context.Context, page *ObjectPage) (err error) {
+	input := page.Status.(*objectPageStatus)
+	prefix := s.getAbsPath(input.path)
+
+	fis, err := ioutil.ReadDir(s.getMultipartRoot())
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	if len(r.Files) == 0 {
-		return IterateDone
-	}
+	for _, fi := range fis {
+		meta, err := readMultipartMeta(filepath.Join(s.getMultipartRoot(), fi.Name()))
+		if err != nil {
+			// The spill dir could be deleted by complete or delete at the same time.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		// Only the object at the path and the objects under it are listed, `a` should not list `ab`.
+		if meta.Path != prefix && !isSubPath(prefix, meta.Path) {
+			continue
+		}
 
-	for _, f := range r.Files {
 		o := s.newObject(true)
-		o.SetContentLength(f.Size)
-		o.Path = f.Name
-		switch f.MimeType {
-		case directoryMimeType:
-			o.Mode = ModeDir
-		default:
-			o.Mode = ModeRead
-		}
+		o.ID = meta.Path
+		o.Path = s.getRelPath(meta.Path)
+		o.Mode |= ModePart
+		o.SetMultipartID(meta.MultipartID)
 		page.Data = append(page.Data, o)
 	}
 
-	input.pageToken = r.NextPageToken
-	return nil
+	return IterateDone
+}
+
+func (s *Storage) nextPartPage(ctx context.Context, page *PartPage) (err error) {
+	input := page.Status.(*partPageStatus)
+
+	parts, err := listSpilledParts(input.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return services.ErrObjectNotExist
+		}
+		return err
+	}
+
+	page.Data = append(page.Data, parts...)
+	return IterateDone
 }
 
 // pathToId converts path to fileId, as we discussed in RFC-14.
@@ -210,12 +955,26 @@ func (s *Storage) pathToId(ctx context.Context, path string) (fileId string, err
 		return fileId, nil
 	}
 
-	pathUnits := strings.Split(path, "/")
-	fileId = "root"
+	pathUnits := strings.Split(s.getWalkPath(path), "/")
+	fileId = s.getWorkDirId()
 	cacheCurrentPath := ""
+	if s.workDirId != "" {
+		cacheCurrentPath = strings.Trim(s.workDir, "/")
+	}
 	// Traverse the whole path, break the loop if we fails at one search
-	for _, v := range pathUnits {
-		fileId, err = s.searchContentInDir(ctx, fileId, v)
+	for i, v := range pathUnits {
+		// Skip empty units which come from the leading slash of a root work dir.
+		if v == "" {
+			continue
+		}
+
+		// Only the last unit could be a file, so there is no need to pick one from duplicate
+		// files and folders for the others.
+		if i == len(pathUnits)-1 {
+			fileId, err = s.searchContentInDir(ctx, fileId, v)
+		} else {
+			fileId, err = s.searchDirInDir(ctx, fileId, v)
+		}
 
 		if fileId == "" || err != nil {
 			break
@@ -242,29 +1001,36 @@ func (s *Storage) read(ctx context.Context, path string, w io.Writer, opt pairSt
 	if err != nil {
 		return 0, err
 	}
-	fileGetCall := s.service.Files.Get(fileId)
-	if opt.HasOffset && !opt.HasSize {
-		rangeBytes := fmt.Sprintf("bytes=%d-", opt.Offset)
-		fileGetCall.Header().Add("Range", rangeBytes)
-	} else if !opt.HasOffset && opt.HasSize {
-		rangeBytes := fmt.Sprintf("bytes=0-%d", opt.Size-1)
-		fileGetCall.Header().Add("Range", rangeBytes)
-	} else if opt.HasOffset && opt.HasSize {
-		rangeBytes := fmt.Sprintf("bytes=%d-%d", opt.Offset, opt.Offset+opt.Size-1)
-		fileGetCall.Header().Add("Range", rangeBytes)
+	if fileId == "" {
+		return 0, services.ErrObjectNotExist
+	}
+
+	var rc io.ReadCloser
+	if opt.HasExportMimeType {
+		rc, err = s.exportFile(ctx, fileId, opt.ExportMimeType, opt)
+	} else {
+		rc, err = s.downloadFile(ctx, fileId, opt)
+		// Google Workspace documents have no binary content, they can only be exported.
+		if err != nil && isFileNotDownloadable(err) {
+			var mimeType string
+			mimeType, err = s.getExportMimeType(ctx, fileId)
+			if err == nil {
+				rc, err = s.exportFile(ctx, fileId, mimeType, opt)
+			}
+		}
 	}
-	f, err := fileGetCall.Context(ctx).Download()
 	if err != nil {
 		return 0, err
 	}
+	defer rc.Close()
 
-	var rc io.ReadCloser
-	rc = f.Body
 	if opt.HasIoCallback {
 		rc = iowrap.CallbackReadCloser(rc, opt.IoCallback)
 	}
 
-	return io.Copy(w, rc)
+	// The body will be closed once ctx is done, but the writer could be slow, check ctx between
+	// every read to abort promptly.
+	return io.Copy(w, newContextReader(ctx, rc))
 }
 
 // Search something in directory by passing it's name and the fileId of the folder.
@@ -272,44 +1038,72 @@ func (s *Storage) read(ctx context.Context, path string, w io.Writer, opt pairSt
 // If nothing is found, we will return an empty string and nil.
 // We will only return non nil if error occurs.
 func (s *Storage) searchContentInDir(ctx context.Context, dirId string, contentName string) (fileId string, err error) {
-	searchArg := fmt.Sprintf("name = '%s' and parents = '%s'", contentName, dirId)
-	fileList, err := s.service.Files.List().Context(ctx).Q(searchArg).Fields("*").Do()
+	return s.searchInDir(ctx, newFileQuery(), s.duplicatePolicy, dirId, contentName)
+}
+
+// searchDirInDir is like searchContentInDir, but only folders will be matched. The oldest one
+// of duplicate folders is always picked, the same as dedupDir, so that contents will not be
+// written into the one which is going to be trashed.
+func (s *Storage) searchDirInDir(ctx context.Context, dirId string, dirName string) (fileId string, err error) {
+	return s.searchInDir(ctx, newFileQuery().equal("mimeType", directoryMimeType), DuplicatePolicyOldest, dirId, dirName)
+}
+
+// searchInDir will search contents matching q with the name in the folder, and pick one of
+// them by duplicate policy, as gdrive allows contents with the same name in a folder.
+func (s *Storage) searchInDir(ctx context.Context, q *query, policy string, dirId string, name string) (fileId string, err error) {
+	files, err := s.listNameInDir(ctx, q, dirId, name)
 	if err != nil {
 		return "", err
 	}
-	// Because we assume that the path is unique, so there would be only two results: One file matches or none
-	if len(fileList.Files) == 0 {
+	if len(files) == 0 {
 		return "", nil
 	}
-	return fileList.Files[0].Id, nil
 
+	f, err := resolveDuplicate(policy, name, files)
+	if err != nil {
+		return "", err
+	}
+	return f.Id, nil
 }
 
 func (s *Storage) stat(ctx context.Context, path string, opt pairStorageStat) (o *Object, err error) {
+	if opt.HasMultipartID {
+		_, err = os.Stat(s.getMultipartDir(opt.MultipartID))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, services.ErrObjectNotExist
+			}
+			return nil, err
+		}
+
+		o = s.newObject(true)
+		o.ID = s.getAbsPath(path)
+		o.Path = path
+		o.Mode |= ModePart
+		o.SetMultipartID(opt.MultipartID)
+		return o, nil
+	}
 
 	content, err := s.pathToId(ctx, path)
 
+	if err != nil {
+		return nil, err
+	}
+
 	if content == "" {
 		return nil, services.ErrObjectNotExist
 	}
 
+	file, err := s.service.Files.Get(content).SupportsAllDrives(true).Context(ctx).Fields("*").Do()
 	if err != nil {
 		return nil, err
 	}
 
-	rp := s.getAbsPath(path)
-	o = s.newObject(true)
-	o.ID = rp
-	o.Path = path
-
-	//TODO: Just a temporary hack, maybe add a helper function to do this?
-	file, _ := s.service.Files.Get(content).Context(ctx).Fields("*").Do()
-
-	if file.MimeType == directoryMimeType {
-		o.Mode |= ModeDir
+	o, err = s.formatFileObject(file, s.getAbsPath(path))
+	if err != nil {
+		return nil, err
 	}
-
-	o.SetContentLength(file.Size)
+	o.Path = path
 
 	return o, nil
 }
@@ -326,23 +1120,35 @@ func (s *Storage) write(ctx context.Context, path string, r io.Reader, size int6
 	// Parent directory of the file
 	var parentsId string
 
-	r = io.LimitReader(r, size)
-
-	if opt.HasIoCallback {
-		r = iowrap.CallbackReader(r, opt.IoCallback)
-	}
-
 	fileId, err := s.pathToId(ctx, path)
 
 	if err != nil {
 		return 0, err
 	}
 
+	file := &drive.File{MimeType: opt.ContentType}
+	// gdrive will convert the content into the Google Workspace document type on upload.
+	if opt.HasConvertMimeType {
+		file.MimeType = opt.ConvertMimeType
+	}
+
+	// Converted content will be different from the uploaded one, so there is no way to check it's md5.
+	// A Google Workspace content type also leads to the conversion.
+	if opt.HasContentMd5 {
+		if opt.HasConvertMimeType {
+			return 0, services.PairUnsupportedError{Pair: WithConvertMimeType(opt.ConvertMimeType)}
+		}
+		if strings.HasPrefix(file.MimeType, workspaceMimeTypePrefix) {
+			return 0, services.PairUnsupportedError{Pair: ps.WithContentType(opt.ContentType)}
+		}
+	}
+
 	// fileId can be empty when err is nil
 	if fileId == "" {
 		// upload
 		dirs, fileName := filepath.Split(s.getAbsPath(path))
 
+		parentsId = s.getWorkDirId()
 		if dirs != "" {
 			parentsId, err = s.createDirs(ctx, dirs)
 			if err != nil {
@@ -351,21 +1157,50 @@ func (s *Storage) write(ctx context.Context, path string, r io.Reader, size int6
 
 		}
 
-		file := &drive.File{
-			Name:    fileName,
-			Parents: []string{parentsId},
+		file.Name = fileName
+		file.Parents = []string{parentsId}
+	} else {
+		// update
+		file.Name = s.getFileName(path)
+	}
+
+	var f *drive.File
+
+	// Content larger than a chunk will be uploaded via resumable upload session, so that
+	// it could be resumed from where it failed instead of uploading from start.
+	if opt.HasUploadSessionURI || size > getChunkSize(opt.ChunkSize) {
+		f, err = s.writeResumable(ctx, fileId, file, r, size, opt)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		r = io.LimitReader(r, size)
+
+		if opt.HasIoCallback {
+			r = iowrap.CallbackReader(r, opt.IoCallback)
+		}
+
+		var mediaOptions []googleapi.MediaOption
+		if opt.HasContentType {
+			mediaOptions = append(mediaOptions, googleapi.ContentType(opt.ContentType))
 		}
-		_, err = s.service.Files.Create(file).Context(ctx).Media(r).Do()
 
+		if fileId == "" {
+			f, err = s.service.Files.Create(file).SupportsAllDrives(true).Context(ctx).Media(r, mediaOptions...).Fields("id", "md5Checksum", "headRevisionId").Do()
+		} else {
+			f, err = s.service.Files.Update(fileId, file).SupportsAllDrives(true).Context(ctx).Media(r, mediaOptions...).Fields("id", "md5Checksum", "headRevisionId").Do()
+		}
 		if err != nil {
 			return 0, err
 		}
+	}
 
-	} else {
-		// update
-		newFile := &drive.File{Name: s.getFileName(path)}
-		_, err = s.service.Files.Update(fileId, newFile).Context(ctx).Media(r).Do()
+	if fileId == "" {
+		s.setCache(s.getAbsPath(path), f.Id)
+	}
 
+	if opt.HasContentMd5 {
+		err = s.checkContentMd5(ctx, path, f, fileId == "", opt.ContentMd5)
 		if err != nil {
 			return 0, err
 		}
@@ -373,3 +1208,107 @@ func (s *Storage) write(ctx context.Context, path string, r io.Reader, size int6
 
 	return size, nil
 }
+
+func (s *Storage) writeAppend(ctx context.Context, o *Object, r io.Reader, size int64, opt pairStorageWriteAppend) (n int64, err error) {
+	f, err := os.OpenFile(s.getAppendPath(o.ID), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, services.ErrObjectNotExist
+		}
+		return 0, err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err = io.Copy(f, io.LimitReader(newContextReader(ctx, r), size))
+	if err == nil && n != size {
+		err = fmt.Errorf("expected %d bytes, actual %d: %w", size, n, io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		// Drop the partially appended content, so that the spool will never be torn.
+		if terr := f.Truncate(fi.Size()); terr != nil {
+			return 0, terr
+		}
+		return 0, err
+	}
+
+	o.SetAppendOffset(fi.Size() + n)
+	return n, nil
+}
+
+func (s *Storage) writeMultipart(ctx context.Context, o *Object, r io.Reader, size int64, index int, opt pairStorageWriteMultipart) (n int64, part *Part, err error) {
+	dir := s.getMultipartDir(o.MustGetMultipartID())
+
+	_, err = os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil, services.ErrObjectNotExist
+		}
+		return 0, nil, err
+	}
+
+	part, err = spillPart(dir, newContextReader(ctx, r), size, index)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, part, nil
+}
+
+// writeResumable will upload content via a resumable upload session. If the upload failed, the
+// session uri will be returned in UploadInterruptedError, so that it could be resumed by
+// writing the same content again with upload_session_uri.
+func (s *Storage) writeResumable(ctx context.Context, fileId string, file *drive.File, r io.Reader, size int64, opt pairStorageWrite) (f *drive.File, err error) {
+	var offset int64
+	uri := opt.UploadSessionURI
+
+	if opt.HasUploadSessionURI {
+		offset, f, err = s.queryUploadSession(ctx, uri, size)
+		if err != nil {
+			return nil, err
+		}
+		// The upload has been finished before.
+		if f != nil {
+			return f, nil
+		}
+
+		// Skip the content which has been persisted.
+		switch v := r.(type) {
+		case io.Seeker:
+			_, err = v.Seek(offset, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+		case io.ReaderAt:
+			r = io.NewSectionReader(v, offset, size-offset)
+		default:
+			return nil, services.PairUnsupportedError{Pair: WithUploadSessionURI(uri)}
+		}
+	} else {
+		uri, err = s.createUploadSession(ctx, fileId, file, opt.ContentType, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	uo := uploadOptions{
+		chunkSize:     opt.ChunkSize,
+		retryDeadline: opt.RetryDeadline,
+	}
+	if opt.HasIoCallback {
+		uo.ioCallback = opt.IoCallback
+	}
+
+	f, err = s.resumableUpload(ctx, uri, r, offset, size, uo)
+	if err != nil {
+		return nil, UploadInterruptedError{SessionURI: uri, Err: formatError(err)}
+	}
+	return f, nil
+}
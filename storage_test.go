@@ -0,0 +1,463 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+func TestWriteContentMd5Mismatch(t *testing.T) {
+	content := []byte("hello")
+	sum := md5.Sum(content)
+	expected := base64.StdEncoding.EncodeToString(sum[:])
+
+	cases := []struct {
+		name   string
+		exists bool
+		// id is the id of the written file.
+		id string
+		// removed is the request expected to remove the broken content.
+		removed string
+	}{
+		{"create", false, "new", "PATCH /drive/v3/files/new"},
+		{"update", true, "id-a", "DELETE /drive/v3/files/id-a/revisions/head"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var removed []string
+			store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+				if serveSearch(w, r, tt.exists) {
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/upload/drive/v3/files"):
+					_, _ = fmt.Fprintf(w, `{"id":"%s","md5Checksum":"%x","headRevisionId":"head"}`, tt.id, md5.Sum(nil))
+				case r.Method == http.MethodPatch || r.Method == http.MethodDelete:
+					body, _ := ioutil.ReadAll(r.Body)
+					if r.Method == http.MethodPatch && !bytes.Contains(body, []byte(`"trashed":true`)) {
+						t.Errorf("patch %s with %s, expect trashed", r.URL.Path, body)
+					}
+					removed = append(removed, r.Method+" "+r.URL.Path)
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			})
+
+			_, err := store.Write("a", bytes.NewReader(content), int64(len(content)), ps.WithContentMd5(expected))
+			if !errors.Is(err, ErrContentMd5Mismatch) {
+				t.Errorf("write got %v, expect content md5 mismatch", err)
+			}
+			if len(removed) != 1 || removed[0] != tt.removed {
+				t.Errorf("removed by %v, expect %s", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestWriteContentMd5Unsupported(t *testing.T) {
+	cases := []struct {
+		name string
+		pair Pair
+	}{
+		{"convert mime type", WithConvertMimeType("application/vnd.google-apps.document")},
+		{"workspace content type", ps.WithContentType("application/vnd.google-apps.document")},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+				if serveSearch(w, r, false) {
+					return
+				}
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			})
+
+			_, err := store.Write("a", strings.NewReader("hello"), 5, ps.WithContentMd5("md5"), tt.pair)
+
+			var e services.PairUnsupportedError
+			if !errors.As(err, &e) {
+				t.Errorf("write got %v, expect PairUnsupportedError", err)
+			}
+		})
+	}
+}
+
+func TestWriteContentMd5Empty(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, false) {
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/upload/drive/v3/files") {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"new","headRevisionId":"head"}`))
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	_, err := store.Write("a", strings.NewReader("hello"), 5, ps.WithContentMd5("md5"))
+	if err != nil {
+		t.Errorf("write got %v, expect the empty md5 checksum skipped", err)
+	}
+}
+
+func TestListMultipartIsolated(t *testing.T) {
+	tempDir := t.TempDir()
+	newStore := func(name, workDir, workDirId string) *Storage {
+		store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		})
+		store.name, store.workDir, store.workDirId, store.tempDir = name, workDir, workDirId, tempDir
+		return store
+	}
+	owner := newStore("test", "/", "dir")
+
+	for _, path := range []string{"a", "ab"} {
+		dir := owner.getMultipartDir(path)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		err := writeMultipartMeta(dir, multipartMeta{Path: owner.getAbsPath(path), MultipartID: path})
+		if err != nil {
+			t.Fatalf("write meta: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		store *Storage
+		path  string
+		// expect is the count of listed multiparts.
+		expect int
+		// owned is whether the parts of the multiparts could be listed.
+		owned bool
+	}{
+		{"owner", owner, "", 2, true},
+		{"owner under path", owner, "a", 1, true},
+		{"another name", newStore("another", "/", "dir"), "", 0, false},
+		{"another work dir", newStore("test", "/", "another"), "", 0, false},
+		{"another work dir path", newStore("test", "/another", "dir"), "", 0, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := tt.store.List(tt.path, ps.WithListMode(ListModePart))
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			count := 0
+			for {
+				_, err = it.Next()
+				if errors.Is(err, IterateDone) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+				count++
+			}
+			if count != tt.expect {
+				t.Errorf("listed %d multiparts, expect %d", count, tt.expect)
+			}
+
+			o := tt.store.newObject(true)
+			o.Mode |= ModePart
+			o.SetMultipartID("a")
+			pi, err := tt.store.ListMultipart(o)
+			if err != nil {
+				t.Fatalf("list multipart: %v", err)
+			}
+			_, err = pi.Next()
+			if owned := !errors.Is(err, services.ErrObjectNotExist); owned != tt.owned {
+				t.Errorf("list parts got %v, expect owned %v", err, tt.owned)
+			}
+		})
+	}
+}
+
+func TestListExcludeTrashed(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/drive/v3/files" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+		if q := r.URL.Query().Get("q"); !strings.Contains(q, "trashed = false") {
+			t.Errorf("query %q includes trashed files", q)
+		}
+		if serveSearch(w, r, true) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"files":[]}`))
+	})
+
+	for _, mode := range []ListMode{ListModeDir, ListModePrefix} {
+		it, err := store.List("dir/", ps.WithListMode(mode))
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if _, err = it.Next(); !errors.Is(err, IterateDone) {
+			t.Errorf("next got %v, expect IterateDone", err)
+		}
+	}
+}
+
+func TestListSkipEmptyPage(t *testing.T) {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if serveSearch(w, r, true) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch token := r.URL.Query().Get("pageToken"); token {
+		case "":
+			_, _ = w.Write([]byte(`{"files":[],"nextPageToken":"next"}`))
+		case "next":
+			_, _ = w.Write([]byte(`{"files":[{"id":"id-a","name":"a","mimeType":"text/plain"}]}`))
+		default:
+			t.Errorf("unexpected page token %q", token)
+		}
+	})
+
+	it, err := store.List("dir", ps.WithListMode(ListModeDir))
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	o, err := it.Next()
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if o.Path != "dir/a" {
+		t.Errorf("listed %s, expect dir/a", o.Path)
+	}
+	if _, err = it.Next(); !errors.Is(err, IterateDone) {
+		t.Errorf("next got %v, expect IterateDone", err)
+	}
+}
+
+// newReplaceTestStorage creates a storage whose paths are cached as `id-<path>`, and the files
+// of ids could be got with their mime types. Any change to the files fails the test.
+func newReplaceTestStorage(t *testing.T, mimeTypes map[string]string) *Storage {
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+		if mimeType, ok := mimeTypes[id]; ok && r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"id":"%s","mimeType":"%s","parents":["root"]}`, id, mimeType)
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	for id := range mimeTypes {
+		store.setCache("/"+strings.TrimPrefix(id, "id-"), id)
+	}
+	return store
+}
+
+func TestMoveRefuseDirectoryDst(t *testing.T) {
+	mimeTypes := map[string]string{
+		"id-dir":       directoryMimeType,
+		"id-dir/a.txt": "text/plain",
+		"id-b":         directoryMimeType,
+		"id-c.txt":     "text/plain",
+	}
+
+	cases := []struct {
+		name string
+		src  string
+		dst  string
+	}{
+		{"dst is the parent of src", "dir/a.txt", "dir"},
+		{"dst is a directory", "c.txt", "b"},
+		{"dst is under src", "dir", "dir/sub"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newReplaceTestStorage(t, mimeTypes)
+
+			err := store.Move(tt.src, tt.dst)
+			if !errors.Is(err, services.ErrObjectModeInvalid) {
+				t.Errorf("move got %v, expect object mode invalid", err)
+			}
+		})
+	}
+}
+
+func TestCopyRefuseDirectoryDst(t *testing.T) {
+	mimeTypes := map[string]string{
+		"id-dir":       directoryMimeType,
+		"id-dir/a.txt": "text/plain",
+		"id-b":         directoryMimeType,
+		"id-c.txt":     "text/plain",
+	}
+
+	cases := []struct {
+		name string
+		src  string
+		dst  string
+	}{
+		{"dst is the parent of src", "dir/a.txt", "dir"},
+		{"dst is a directory", "c.txt", "b"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newReplaceTestStorage(t, mimeTypes)
+
+			err := store.Copy(tt.src, tt.dst)
+			if !errors.Is(err, services.ErrObjectModeInvalid) {
+				t.Errorf("copy got %v, expect object mode invalid", err)
+			}
+		})
+	}
+}
+
+func TestMkDirConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	var created []string
+	store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			files := make([]string, 0, len(created))
+			for _, id := range created {
+				files = append(files, fmt.Sprintf(`{"id":"%s","mimeType":"%s"}`, id, directoryMimeType))
+			}
+			_, _ = fmt.Fprintf(w, `{"files":[%s]}`, strings.Join(files, ","))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			created = append(created, fmt.Sprintf("id-%d", len(created)))
+			_, _ = fmt.Fprintf(w, `{"id":"%s"}`, created[len(created)-1])
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	})
+
+	var wg sync.WaitGroup
+	ids := make([]string, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var err error
+			ids[i], err = store.mkDir(context.Background(), "root", "dir")
+			if err != nil {
+				t.Errorf("mkdir: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(created) != 1 {
+		t.Errorf("created %v, expect only one directory", created)
+	}
+	for _, id := range ids {
+		if id != "id-0" {
+			t.Errorf("mkdir got %s, expect id-0", id)
+		}
+	}
+	if n := len(store.dirLocks.locks); n != 0 {
+		t.Errorf("%d locks are not released", n)
+	}
+}
+
+func TestDedupDir(t *testing.T) {
+	cases := []struct {
+		name string
+		// id is the id of the directory we created.
+		id string
+		// trashed is whether our directory should be trashed.
+		trashed bool
+	}{
+		{"lose", "id-new", true},
+		{"win", "id-old", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var trashed []string
+			store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+					_, _ = fmt.Fprintf(w, `{"files":[
+						{"id":"id-new","mimeType":"%[1]s","createdTime":"2021-01-02T00:00:00Z","modifiedTime":"2021-01-02T00:00:00Z"},
+						{"id":"id-old","mimeType":"%[1]s","createdTime":"2021-01-01T00:00:00Z","modifiedTime":"2021-01-01T00:00:00Z"}
+					]}`, directoryMimeType)
+				case r.Method == http.MethodPatch:
+					body, _ := ioutil.ReadAll(r.Body)
+					if !bytes.Contains(body, []byte(`"trashed":true`)) {
+						t.Errorf("patch %s with %s, expect trashed", r.URL.Path, body)
+					}
+					trashed = append(trashed, strings.TrimPrefix(r.URL.Path, "/drive/v3/files/"))
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			})
+			// Lookups should pick the same directory as dedupDir whatever the duplicate policy is.
+			store.duplicatePolicy = DuplicatePolicyNewest
+
+			id, err := store.searchDirInDir(context.Background(), "root", "dir")
+			if err != nil || id != "id-old" {
+				t.Errorf("search got %s, %v, expect id-old", id, err)
+			}
+
+			id, err = store.dedupDir(context.Background(), "root", "dir", tt.id)
+			if err != nil || id != "id-old" {
+				t.Errorf("dedup got %s, %v, expect id-old", id, err)
+			}
+			if tt.trashed != (len(trashed) == 1 && trashed[0] == tt.id) || len(trashed) > 1 {
+				t.Errorf("trashed %v, expect %s trashed %v", trashed, tt.id, tt.trashed)
+			}
+		})
+	}
+}
+
+func TestReplaceDeletedDst(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func(s *Storage) error
+	}{
+		{"copy", func(s *Storage) error { return s.Copy("src", "dst") }},
+		{"move", func(s *Storage) error { return s.Move("src", "dst") }},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+				// dst has been deleted by the lost request before retried.
+				if r.Method == http.MethodDelete && r.URL.Path == "/drive/v3/files/id-dst" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method + " " + r.URL.Path {
+				case "GET /drive/v3/files/id-src", "GET /drive/v3/files/id-dst":
+					_, _ = w.Write([]byte(`{"mimeType":"text/plain","parents":["root"]}`))
+				case "POST /drive/v3/files/id-src/copy", "PATCH /drive/v3/files/id-src":
+					_, _ = w.Write([]byte(`{"id":"id-new"}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			})
+			store.setCache("/src", "id-src")
+			store.setCache("/dst", "id-dst")
+
+			if err := tt.fn(store); err != nil {
+				t.Errorf("%s got %v, expect the deleted dst ignored", tt.name, err)
+			}
+		})
+	}
+}
@@ -20,3 +20,24 @@ func TestCopier(t *testing.T) {
 	}
 	tests.TestCopier(t, setupTest(t))
 }
+
+func TestMover(t *testing.T) {
+	if os.Getenv("STORAGE_GDRIVE_INTEGRATION_TEST") != "on" {
+		t.Skipf("STORAGE_GDRIVE_INTEGRATION_TEST is not 'on', skipped")
+	}
+	tests.TestMover(t, setupTest(t))
+}
+
+func TestMultiparter(t *testing.T) {
+	if os.Getenv("STORAGE_GDRIVE_INTEGRATION_TEST") != "on" {
+		t.Skipf("STORAGE_GDRIVE_INTEGRATION_TEST is not 'on', skipped")
+	}
+	tests.TestMultiparter(t, setupTest(t))
+}
+
+func TestAppender(t *testing.T) {
+	if os.Getenv("STORAGE_GDRIVE_INTEGRATION_TEST") != "on" {
+		t.Skipf("STORAGE_GDRIVE_INTEGRATION_TEST is not 'on', skipped")
+	}
+	tests.TestAppender(t, setupTest(t))
+}
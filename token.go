@@ -0,0 +1,322 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/pkg/credential"
+	"github.com/beyondstorage/go-storage/v4/services"
+)
+
+// TokenStore persists the OAuth2 token of a user, so that the refreshed token could be reused
+// after the process restarted.
+type TokenStore interface {
+	// Load returns the stored token, nil if there is no token stored yet.
+	Load() (*oauth2.Token, error)
+	// Save stores the token, it will be called every time the token is refreshed.
+	Save(token *oauth2.Token) error
+}
+
+// NewFileTokenStore will create a TokenStore which stores the token as JSON in the file at path.
+func NewFileTokenStore(path string) TokenStore {
+	return &fileTokenStore{path: path}
+}
+
+type fileTokenStore struct {
+	path string
+}
+
+func (s *fileTokenStore) Load() (*oauth2.Token, error) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	token := &oauth2.Token{}
+	err = json.Unmarshal(content, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *fileTokenStore) Save(token *oauth2.Token) (err error) {
+	content, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return err
+	}
+	// Write into a temp file and rename it, so that the token will never be half written.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(content)
+	if err != nil {
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// AuthCodeConfig describes an OAuth2 client to get the token of a user by the authorization
+// code flow.
+//
+// Ref: https://developers.google.com/identity/protocols/oauth2/native-app
+type AuthCodeConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// TokenURL is the endpoint to exchange and refresh tokens, default to Google's.
+	TokenURL string
+}
+
+// AuthCodeURL returns the URL for the user to grant the access of gdrive, the authorization
+// code will be sent to RedirectURL.
+func (c AuthCodeConfig) AuthCodeURL(state string) string {
+	// A refresh token will only be returned for offline access, and consent is required to
+	// get it again once the user has granted the access before.
+	return c.config().AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+}
+
+// Exchange will exchange the authorization code for the token and save it into store if not nil.
+// The refresh token of the returned token could be used as refresh_token pair.
+func (c AuthCodeConfig) Exchange(ctx context.Context, code string, store TokenStore) (*oauth2.Token, error) {
+	token, err := c.config().Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		err = store.Save(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+func (c AuthCodeConfig) config() *oauth2.Config {
+	cfg := &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{drive.DriveScope},
+	}
+	if c.TokenURL != "" {
+		cfg.Endpoint.TokenURL = c.TokenURL
+	}
+	return cfg
+}
+
+// authOptions controls how the token of a user will be got and stored.
+type authOptions struct {
+	refreshToken string
+	tokenFile    string
+	tokenStore   TokenStore
+	tokenURL     string
+}
+
+// getTokenStore returns the token store to use, token_store is preferred over token_file.
+func (ao authOptions) getTokenStore() TokenStore {
+	if ao.tokenStore != nil {
+		return ao.tokenStore
+	}
+	if ao.tokenFile != "" {
+		return NewFileTokenStore(ao.tokenFile)
+	}
+	return nil
+}
+
+// getUserKey returns the identity of the user authorized with, an empty key means the user is
+// identified by the credential itself.
+func (ao authOptions) getUserKey() string {
+	switch {
+	case ao.tokenStore != nil:
+		// Stores created by users are identified by themselves, they should be pointers.
+		return fmt.Sprintf("store:%T:%p", ao.tokenStore, ao.tokenStore)
+	case ao.tokenFile != "":
+		path, err := filepath.Abs(ao.tokenFile)
+		if err != nil {
+			path = ao.tokenFile
+		}
+		return "file:" + path
+	case ao.refreshToken != "":
+		return "refresh_token:" + ao.refreshToken
+	default:
+		return ""
+	}
+}
+
+// newTokenSource will create the token source from the credential, which could be:
+//
+//   - hmac:<client_id>:<client_secret> of an OAuth2 client, with the token of the user from
+//     refresh_token or the token store.
+//   - file or base64 of a client secret JSON of installed or web apps, with the token of the user
+//     like above.
+//   - file or base64 of other JSON supported by google.CredentialsFromJSON, e.g. service accounts.
+func newTokenSource(ctx context.Context, cred string, ao authOptions) (oauth2.TokenSource, error) {
+	var credJSON []byte
+
+	cp, err := credential.Parse(cred)
+	if err != nil {
+		return nil, err
+	}
+	switch cp.Protocol() {
+	case credential.ProtocolHmac:
+		clientID, clientSecret := cp.Hmac()
+		cfg := AuthCodeConfig{ClientID: clientID, ClientSecret: clientSecret, TokenURL: ao.tokenURL}
+		return newUserTokenSource(ctx, cfg.config(), ao)
+	case credential.ProtocolFile:
+		credJSON, err = ioutil.ReadFile(cp.File())
+		if err != nil {
+			return nil, err
+		}
+	case credential.ProtocolBase64:
+		credJSON, err = base64.StdEncoding.DecodeString(cp.Base64())
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, services.PairUnsupportedError{Pair: ps.WithCredential(cred)}
+	}
+
+	if isClientSecretJSON(credJSON) {
+		cfg, err := google.ConfigFromJSON(credJSON, drive.DriveScope)
+		if err != nil {
+			return nil, err
+		}
+		if ao.tokenURL != "" {
+			cfg.Endpoint.TokenURL = ao.tokenURL
+		}
+		return newUserTokenSource(ctx, cfg, ao)
+	}
+
+	// Other credentials carry the way to get tokens by themselves, e.g. service accounts.
+	switch {
+	case ao.refreshToken != "":
+		return nil, services.PairUnsupportedError{Pair: WithRefreshToken(ao.refreshToken)}
+	case ao.tokenStore != nil:
+		return nil, services.PairUnsupportedError{Pair: WithTokenStore(ao.tokenStore)}
+	case ao.tokenFile != "":
+		return nil, services.PairUnsupportedError{Pair: WithTokenFile(ao.tokenFile)}
+	}
+
+	// Loading token source from binary data.
+	// DriveScope means full control of gdrive
+	creds, err := google.CredentialsFromJSON(ctx, credJSON, drive.DriveScope)
+	if err != nil {
+		return nil, err
+	}
+	return creds.TokenSource, nil
+}
+
+// isClientSecretJSON checks whether the content is a client secret JSON downloaded from Google
+// Cloud Console, which contains no token.
+func isClientSecretJSON(content []byte) bool {
+	var v struct {
+		Installed json.RawMessage `json:"installed"`
+		Web       json.RawMessage `json:"web"`
+	}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return false
+	}
+	return v.Installed != nil || v.Web != nil
+}
+
+// newUserTokenSource will create the token source of a user with the OAuth2 client.
+// The token in the token store is preferred, as the refresh token could be rotated.
+func newUserTokenSource(ctx context.Context, cfg *oauth2.Config, ao authOptions) (oauth2.TokenSource, error) {
+	var token *oauth2.Token
+	var err error
+	store := ao.getTokenStore()
+	if store != nil {
+		token, err = store.Load()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token == nil {
+		token = &oauth2.Token{}
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = ao.refreshToken
+	}
+	if token.RefreshToken == "" {
+		return nil, services.PairRequiredError{Keys: []string{"refresh_token"}}
+	}
+
+	ts := cfg.TokenSource(ctx, token)
+	if store != nil {
+		return newStoredTokenSource(ts, store, token), nil
+	}
+	return ts, nil
+}
+
+// storedTokenSource will save the token into store once it's refreshed.
+type storedTokenSource struct {
+	src   oauth2.TokenSource
+	store TokenStore
+
+	mu sync.Mutex
+	// accessToken is the access token saved last time.
+	accessToken string
+}
+
+func newStoredTokenSource(src oauth2.TokenSource, store TokenStore, token *oauth2.Token) *storedTokenSource {
+	return &storedTokenSource{
+		src:         src,
+		store:       store,
+		accessToken: token.AccessToken,
+	}
+}
+
+func (ts *storedTokenSource) Token() (*oauth2.Token, error) {
+	token, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if token.AccessToken == ts.accessToken {
+		return token, nil
+	}
+	// The refresh token could be rotated while refreshing, fail the request instead of
+	// losing it silently.
+	err = ts.store.Save(token)
+	if err != nil {
+		return nil, err
+	}
+	ts.accessToken = token.AccessToken
+	return token, nil
+}
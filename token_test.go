@@ -0,0 +1,241 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/beyondstorage/go-storage/v4/pkg/httpclient"
+	"github.com/beyondstorage/go-storage/v4/services"
+)
+
+// newTokenServer will create a token endpoint which checks the form and returns access token
+// with a rotated refresh token.
+func newTokenServer(t *testing.T, form map[string]string) (srv *httptest.Server, count *int32) {
+	count = new(int32)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(count, 1)
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		for k, v := range form {
+			if got := r.PostForm.Get(k); got != v {
+				t.Errorf("form %s got %q, expect %q", k, got, v)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","refresh_token":"rotated","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, count
+}
+
+func TestFileTokenStore(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token", "token.json"))
+
+	token, err := store.Load()
+	if err != nil || token != nil {
+		t.Fatalf("load empty store got %v, %v", token, err)
+	}
+
+	err = store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	token, err = store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if token == nil || token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("load got %+v", token)
+	}
+}
+
+func TestNewTokenSourceRefresh(t *testing.T) {
+	srv, _ := newTokenServer(t, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": "refresh",
+	})
+
+	cases := []struct {
+		name string
+		cred string
+	}{
+		{"client id and secret", "hmac:id:secret"},
+		{"client secret json", "base64:" + base64.StdEncoding.EncodeToString([]byte(
+			`{"installed":{"client_id":"id","client_secret":"secret","redirect_uris":["http://localhost"]}}`,
+		))},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+			ts, err := newTokenSource(context.Background(), tt.cred, authOptions{
+				refreshToken: "refresh",
+				tokenStore:   store,
+				tokenURL:     srv.URL,
+			})
+			if err != nil {
+				t.Fatalf("new token source: %v", err)
+			}
+			token, err := ts.Token()
+			if err != nil {
+				t.Fatalf("token: %v", err)
+			}
+			if token.AccessToken != "access" {
+				t.Errorf("access token got %q", token.AccessToken)
+			}
+
+			saved, err := store.Load()
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if saved == nil || saved.RefreshToken != "rotated" {
+				t.Errorf("saved token got %+v, expect the rotated refresh token", saved)
+			}
+		})
+	}
+}
+
+func TestNewTokenSourcePreferStore(t *testing.T) {
+	srv, count := newTokenServer(t, map[string]string{
+		"refresh_token": "refresh",
+	})
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+
+	// The store is empty, the refresh token from pair will be used.
+	ts, err := newTokenSource(context.Background(), "hmac:id:secret", authOptions{
+		refreshToken: "refresh",
+		tokenStore:   store,
+		tokenURL:     srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("new token source: %v", err)
+	}
+	if _, err = ts.Token(); err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	// The valid token in the store should be used without refreshing.
+	ts, err = newTokenSource(context.Background(), "hmac:id:secret", authOptions{
+		refreshToken: "outdated",
+		tokenStore:   store,
+		tokenURL:     srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("new token source: %v", err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if token.AccessToken != "access" {
+		t.Errorf("access token got %q", token.AccessToken)
+	}
+	if got := atomic.LoadInt32(count); got != 1 {
+		t.Errorf("token endpoint called %d times, expect 1", got)
+	}
+}
+
+func TestNewTokenSourceRequireRefreshToken(t *testing.T) {
+	_, err := newTokenSource(context.Background(), "hmac:id:secret", authOptions{})
+
+	var e services.PairRequiredError
+	if !errors.As(err, &e) {
+		t.Errorf("new token source got %v, expect PairRequiredError", err)
+	}
+}
+
+func TestAuthCodeConfigExchange(t *testing.T) {
+	srv, _ := newTokenServer(t, map[string]string{
+		"grant_type":   "authorization_code",
+		"code":         "code",
+		"redirect_uri": "http://localhost",
+	})
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+
+	cfg := AuthCodeConfig{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost",
+		TokenURL:     srv.URL,
+	}
+	token, err := cfg.Exchange(context.Background(), "code", store)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if token.RefreshToken != "rotated" {
+		t.Errorf("refresh token got %q", token.RefreshToken)
+	}
+
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if saved == nil || saved.AccessToken != "access" || saved.RefreshToken != "rotated" {
+		t.Errorf("saved token got %+v", saved)
+	}
+}
+
+func TestNewTokenSourceUnsupportedPairs(t *testing.T) {
+	cred := "base64:" + base64.StdEncoding.EncodeToString([]byte(`{"type":"service_account"}`))
+
+	cases := []struct {
+		name string
+		ao   authOptions
+	}{
+		{"refresh token", authOptions{refreshToken: "refresh"}},
+		{"token file", authOptions{tokenFile: "token.json"}},
+		{"token store", authOptions{tokenStore: NewFileTokenStore("token.json")}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTokenSource(context.Background(), cred, tt.ao)
+
+			var e services.PairUnsupportedError
+			if !errors.As(err, &e) {
+				t.Errorf("new token source got %v, expect PairUnsupportedError", err)
+			}
+		})
+	}
+}
+
+func TestNewDriveServiceRefreshWithHTTPClient(t *testing.T) {
+	var refreshed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			// The http client created by httpclient never follows redirects, while the
+			// default one does.
+			http.Redirect(w, r, "/token/redirected", http.StatusFound)
+		case "/token/redirected":
+			atomic.AddInt32(&refreshed, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3600}`))
+		}
+	}))
+	defer srv.Close()
+
+	ro := newRetryOptions()
+	ro.maxAttempts = 1
+	ao := authOptions{refreshToken: "refresh", tokenURL: srv.URL + "/token"}
+
+	hc, _, err := newDriveService("hmac:id:secret", &httpclient.Options{}, ao, ro, &rateLimiter{})
+	if err != nil {
+		t.Fatalf("new drive service: %v", err)
+	}
+	res, err := hc.Get(srv.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Errorf("get should fail as the token endpoint is redirected")
+	}
+	if n := atomic.LoadInt32(&refreshed); n != 0 {
+		t.Errorf("token refreshed by the default http client %d times", n)
+	}
+}
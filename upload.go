@@ -0,0 +1,269 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// defaultRetryDeadline is the same as the retry deadline used by google api client.
+	defaultRetryDeadline = 32 * time.Second
+
+	// statusResumeIncomplete means the chunk has been received, but the upload is not finished yet.
+	statusResumeIncomplete = 308
+)
+
+// getChunkSize will round the chunk size up to a multiple of googleapi.MinUploadChunkSize,
+// as required by gdrive resumable upload.
+func getChunkSize(size int64) int64 {
+	if size <= 0 {
+		return googleapi.DefaultUploadChunkSize
+	}
+	if size%googleapi.MinUploadChunkSize != 0 {
+		size += googleapi.MinUploadChunkSize - size%googleapi.MinUploadChunkSize
+	}
+	return size
+}
+
+// createUploadSession will start a resumable upload session and return it's session uri.
+// A new file will be created if fileId is empty, otherwise the content of fileId will be updated.
+// Pass a negative size if the size of content is unknown, and an empty contentType to let gdrive detect it.
+// Ref: https://developers.google.com/drive/api/v3/manage-uploads#resumable
+func (s *Storage) createUploadSession(ctx context.Context, fileId string, f *drive.File, contentType string, size int64) (uri string, err error) {
+	body, err := googleapi.WithoutDataWrapper.JSONReader(f)
+	if err != nil {
+		return "", err
+	}
+
+	method, urls := http.MethodPost, googleapi.ResolveRelative(s.service.BasePath, "/upload/drive/v3/files")
+	if fileId != "" {
+		method, urls = http.MethodPatch, urls+"/"+url.PathEscape(fileId)
+	}
+
+	params := url.Values{}
+	params.Set("uploadType", "resumable")
+	params.Set("fields", "id,md5Checksum,headRevisionId")
+	params.Set("supportsAllDrives", "true")
+	urls += "?" + params.Encode()
+
+	req, err := http.NewRequest(method, urls, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	// size could be unknown while creating the session for multipart.
+	if size >= 0 {
+		req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+	}
+	if contentType != "" {
+		req.Header.Set("X-Upload-Content-Type", contentType)
+	}
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer googleapi.CloseBody(res)
+
+	err = googleapi.CheckResponse(res)
+	if err != nil {
+		return "", err
+	}
+	return res.Header.Get("Location"), nil
+}
+
+// queryUploadSession will return how many bytes have been persisted in the upload session.
+// The uploaded file will be returned if the upload has been completed.
+func (s *Storage) queryUploadSession(ctx context.Context, uri string, size int64) (offset int64, f *drive.File, err error) {
+	return s.uploadChunk(ctx, uri, nil, 0, size)
+}
+
+// uploadChunk will upload a chunk starting from offset, and return the offset gdrive has persisted.
+// The uploaded file will be returned if the upload has been completed.
+//
+// An empty chunk is used to query the status of the upload session.
+func (s *Storage) uploadChunk(ctx context.Context, uri string, chunk []byte, offset, size int64) (committed int64, f *drive.File, err error) {
+	req, err := http.NewRequest(http.MethodPut, uri, bytes.NewReader(chunk))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(chunk) == 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+	}
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer googleapi.CloseBody(res)
+
+	if res.StatusCode == statusResumeIncomplete {
+		// Range looks like `bytes=0-42`, and it will be missing if nothing has been persisted.
+		r := res.Header.Get("Range")
+		if r == "" {
+			return 0, nil, nil
+		}
+		end, err := strconv.ParseInt(r[strings.LastIndex(r, "-")+1:], 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("parse range %q: %w", r, err)
+		}
+		return end + 1, nil, nil
+	}
+
+	err = googleapi.CheckResponse(res)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	f = &drive.File{}
+	err = json.NewDecoder(res.Body).Decode(f)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, f, nil
+}
+
+// uploadOptions controls how the content will be uploaded in a resumable upload session.
+type uploadOptions struct {
+	chunkSize     int64
+	retryDeadline time.Duration
+	ioCallback    func([]byte)
+}
+
+// cancelUploadSession will cancel an unfinished upload session, it's ok to cancel a finished
+// or canceled session.
+func (s *Storage) cancelUploadSession(ctx context.Context, uri string) (err error) {
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	// gdrive returns 499 for a canceled session and 404 for a session which doesn't exist
+	// any more, both of them mean the session has gone.
+	googleapi.CloseBody(res)
+	return nil
+}
+
+// resumableUpload will upload content from r into the upload session chunk by chunk, starting
+// from offset. Every chunk will be retried until retryDeadline exceeded, and the io callback will
+// be called after the chunk has been persisted.
+func (s *Storage) resumableUpload(ctx context.Context, uri string, r io.Reader, offset, size int64, uo uploadOptions) (f *drive.File, err error) {
+	chunkSize := getChunkSize(uo.chunkSize)
+	retryDeadline := uo.retryDeadline
+	if retryDeadline <= 0 {
+		retryDeadline = defaultRetryDeadline
+	}
+
+	buf := make([]byte, chunkSize)
+	r = newContextReader(ctx, r)
+	for f == nil {
+		// Reading the content could take a long time, don't start another chunk if canceled.
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// All content has been persisted, send an empty request to finish the upload.
+		if offset >= size {
+			_, f, err = s.queryUploadSession(ctx, uri, size)
+			if err != nil {
+				return nil, err
+			}
+			if f == nil {
+				return nil, fmt.Errorf("upload session is not finished after all content uploaded")
+			}
+			break
+		}
+
+		n := size - offset
+		if n > chunkSize {
+			n = chunkSize
+		}
+		chunk := buf[:n]
+
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		start := offset
+		for f == nil && offset-start < n {
+			offset, f, err = s.uploadChunkWithRetry(ctx, uri, chunk[offset-start:], offset, size, retryDeadline)
+			if err != nil {
+				return nil, err
+			}
+			if offset < start {
+				return nil, fmt.Errorf("upload session persisted offset %d is behind the chunk %d", offset, start)
+			}
+		}
+
+		if uo.ioCallback != nil {
+			uo.ioCallback(chunk)
+		}
+	}
+	return f, nil
+}
+
+// uploadChunkWithRetry will retry the chunk with exponential backoff until deadline exceeded.
+func (s *Storage) uploadChunkWithRetry(ctx context.Context, uri string, chunk []byte, offset, size int64, deadline time.Duration) (committed int64, f *drive.File, err error) {
+	start := time.Now()
+	pause := time.Second
+
+	for {
+		committed, f, err = s.uploadChunk(ctx, uri, chunk, offset, size)
+		if err == nil || !isRetryableError(err) || time.Since(start)+pause > deadline {
+			return committed, f, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(pause):
+		}
+		pause *= 2
+
+		// The chunk could be partially persisted before failure, so we need to check
+		// where to continue.
+		committed, f, err = s.queryUploadSession(ctx, uri, size)
+		if err != nil || f != nil || committed != offset {
+			return committed, f, err
+		}
+	}
+}
+
+// isRetryableError checks whether the request could be retried.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var e *googleapi.Error
+	if errors.As(err, &e) {
+		return e.Code == http.StatusTooManyRequests || e.Code >= http.StatusInternalServerError || isRateLimited(err)
+	}
+	// Only errors of the connection are worth retrying. Others are mostly returned while parsing
+	// a malformed response, which won't be better in the next attempt.
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -2,19 +2,24 @@ package gdrive
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 
-	. "github.com/dgraph-io/ristretto"
-
 	ps "github.com/beyondstorage/go-storage/v4/pairs"
 	"github.com/beyondstorage/go-storage/v4/pkg/credential"
 	"github.com/beyondstorage/go-storage/v4/pkg/httpclient"
@@ -23,25 +28,38 @@ import (
 )
 
 const (
-	numCounters = 1e7     // number of keys to track frequency of (10M).
-	maxCost     = 1 << 30 // maximum cost of cache (1GB).
-	bufferItems = 64      // number of keys per Get buffer.
-	cost        = 1
-	expireTime  = 100
+	// appendRootName is the dir under temp dir to spool the content of append objects.
+	appendRootName = "go-service-gdrive-append"
 )
 
 // Storage is the example client.
 type Storage struct {
 	name         string
 	workDir      string
+	tempDir      string
+	client       *http.Client
 	service      *drive.Service
-	cache        *Cache
+	cache        Cache
 	defaultPairs DefaultStoragePairs
 	features     StorageFeatures
 
+	// driveId is the id of the shared drive, empty means user's My Drive.
+	driveId string
+	// duplicatePolicy decides which content to use if there are contents with the same name in a folder.
+	duplicatePolicy string
+	// dirLocks serializes creating directories with the same name under the same parent.
+	dirLocks keyedMutex
+	// workDirId is the fileId of the work dir, paths will be resolved from it instead of the root if specified.
+	workDirId string
+	// exportMimeTypes maps Google Workspace document mime types to the mime types they will be exported into.
+	exportMimeTypes map[string]string
+
 	types.UnimplementedStorager
+	types.UnimplementedAppender
 	types.UnimplementedDirer
 	types.UnimplementedCopier
+	types.UnimplementedMover
+	types.UnimplementedMultiparter
 }
 
 // String implements Storager.String
@@ -57,109 +75,389 @@ func NewStorager(pairs ...types.Pair) (types.Storager, error) {
 	return newStorager(pairs...)
 }
 
-func newStorager(pairs ...types.Pair) (store *Storage, err error) {
+// Service is the gdrive service, every shared drive will be treated as a storage.
+type Service struct {
+	credential   string
+	client       *http.Client
+	service      *drive.Service
+	defaultPairs DefaultServicePairs
+	features     ServiceFeatures
+
+	types.UnimplementedServicer
+}
+
+// String implements Servicer.String
+func (s *Service) String() string {
+	return "Servicer gdrive"
+}
+
+// New will create a new gdrive service.
+func New(pairs ...types.Pair) (types.Servicer, types.Storager, error) {
+	return newServicerAndStorager(pairs...)
+}
+
+// NewServicer will create Servicer only.
+func NewServicer(pairs ...types.Pair) (types.Servicer, error) {
+	return newServicer(pairs...)
+}
+
+func newServicer(pairs ...types.Pair) (srv *Service, err error) {
 	defer func() {
 		if err != nil {
-			err = services.InitError{Op: "new_storager", Type: Type, Err: formatError(err), Pairs: pairs}
+			err = services.InitError{Op: "new_servicer", Type: Type, Err: formatError(err), Pairs: pairs}
 		}
 	}()
 
-	opt, err := parsePairStorageNew(pairs)
+	opt, err := parsePairServiceNew(pairs)
 	if err != nil {
 		return nil, err
 	}
 
-	store = &Storage{
-		name:    opt.Name,
-		workDir: "/",
+	srv = &Service{
+		credential: opt.Credential,
 	}
 
-	// Init cache for storager
-	ch, err := initCache(numCounters, maxCost, bufferItems)
+	if opt.HasDefaultServicePairs {
+		srv.defaultPairs = opt.DefaultServicePairs
+	}
+	if opt.HasServiceFeatures {
+		srv.features = opt.ServiceFeatures
+	}
+
+	ao, ro, limiter, err := newClientOptions(opt)
 	if err != nil {
 		return nil, err
 	}
-	store.cache = ch
 
-	if opt.HasWorkDir {
-		store.workDir = opt.WorkDir
+	srv.client, srv.service, err = newDriveService(opt.Credential, opt.HTTPClientOptions, ao, ro, limiter)
+	if err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+func newServicerAndStorager(pairs ...types.Pair) (srv *Service, store *Storage, err error) {
+	srv, err = newServicer(pairs...)
+	if err != nil {
+		return
 	}
 
-	ctx := context.Background()
+	store, err = newStorager(pairs...)
+	if err != nil {
+		return
+	}
+	return
+}
 
-	// Google drive only support authorized by Oauth2
-	// Ref:https://developers.google.com/drive/api/v3/about-auth
-	hc := httpclient.New(opt.HTTPClientOptions)
+func newStorager(pairs ...types.Pair) (store *Storage, err error) {
+	defer func() {
+		if err != nil {
+			err = services.InitError{Op: "new_storager", Type: Type, Err: formatError(err), Pairs: pairs}
+		}
+	}()
 
-	var credJSON []byte
+	opt, err := parsePairStorageNew(pairs)
+	if err != nil {
+		return nil, err
+	}
 
-	cp, err := credential.Parse(opt.Credential)
+	// Storagers accept the same pairs as servicers to create the client.
+	copt, err := parsePairServiceNew(pairs)
 	if err != nil {
 		return nil, err
 	}
-	switch cp.Protocol() {
-	case credential.ProtocolFile:
-		credJSON, err = ioutil.ReadFile(cp.File())
-		if err != nil {
-			return nil, err
+	ao, ro, limiter, err := newClientOptions(copt)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, srv, err := newDriveService(opt.Credential, opt.HTTPClientOptions, ao, ro, limiter)
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(opt, hc, srv)
+}
+
+// newStorage will create a storage with an authorized client, so that storages created by
+// Service could share the same client.
+func newStorage(opt pairStorageNew, hc *http.Client, srv *drive.Service) (store *Storage, err error) {
+	store = &Storage{
+		name:            opt.Name,
+		workDir:         "/",
+		tempDir:         os.TempDir(),
+		client:          hc,
+		service:         srv,
+		duplicatePolicy: DuplicatePolicyNewest,
+	}
+
+	// Init cache for storager
+	if opt.HasCache {
+		store.cache = opt.Cache
+	} else {
+		cacheSize, cacheTTL := int64(defaultCacheSize), defaultCacheTTL
+		if opt.HasCacheSize {
+			cacheSize = opt.CacheSize
+		}
+		if opt.HasCacheTTL {
+			cacheTTL = opt.CacheTTL
+		}
+		if cacheSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithCacheSize(cacheSize)}
+		}
+		if cacheTTL < 0 {
+			return nil, services.PairUnsupportedError{Pair: WithCacheTTL(cacheTTL)}
+		}
+		store.cache = NewLRUCache(int(cacheSize), cacheTTL)
+	}
+
+	if opt.HasWorkDir {
+		store.workDir = opt.WorkDir
+	}
+	store.exportMimeTypes = make(map[string]string, len(defaultExportMimeTypes))
+	for k, v := range defaultExportMimeTypes {
+		store.exportMimeTypes[k] = v
+	}
+	if opt.HasExportMimeTypes {
+		for k, v := range opt.ExportMimeTypes {
+			store.exportMimeTypes[k] = v
+		}
+	}
+	if opt.HasDriveID {
+		store.driveId = opt.DriveID
+	}
+	if opt.HasDuplicatePolicy {
+		if !isDuplicatePolicyValid(opt.DuplicatePolicy) {
+			return nil, services.PairUnsupportedError{Pair: WithDuplicatePolicy(opt.DuplicatePolicy)}
 		}
-	case credential.ProtocolBase64:
-		credJSON, err = base64.StdEncoding.DecodeString(cp.Base64())
+		store.duplicatePolicy = opt.DuplicatePolicy
+	}
+	if opt.HasTempDir {
+		store.tempDir = opt.TempDir
+	}
+	if opt.HasDefaultStoragePairs {
+		store.defaultPairs = opt.DefaultStoragePairs
+	}
+	if opt.HasStorageFeatures {
+		store.features = opt.StorageFeatures
+	}
+	if opt.HasWorkDirID {
+		// Make sure the work dir is a folder we can see, the folder could be shared with us
+		// and not under our My Drive.
+		f, err := srv.Files.Get(opt.WorkDirID).SupportsAllDrives(true).Fields("id", "mimeType").Do()
 		if err != nil {
 			return nil, err
 		}
-	default:
-		return nil, services.PairUnsupportedError{Pair: ps.WithCredential(opt.Credential)}
+		if f.MimeType != directoryMimeType {
+			return nil, fmt.Errorf("%w: work dir %s is not a folder", services.ErrObjectModeInvalid, opt.WorkDirID)
+		}
+		store.workDirId = f.Id
 	}
 
-	// Loading token source from binary data.
-	// DriveScope means full control of gdrive
-	creds, err := google.CredentialsFromJSON(ctx, credJSON, drive.DriveScope)
+	return store, nil
+}
+
+// newClientOptions will parse the pairs to create the drive client, which are shared by
+// servicers and storagers.
+func newClientOptions(opt pairServiceNew) (ao authOptions, ro retryOptions, limiter *rateLimiter, err error) {
+	if opt.HasRefreshToken {
+		ao.refreshToken = opt.RefreshToken
+	}
+	if opt.HasTokenFile {
+		ao.tokenFile = opt.TokenFile
+	}
+	if opt.HasTokenStore {
+		ao.tokenStore = opt.TokenStore
+	}
+	if opt.HasTokenURL {
+		ao.tokenURL = opt.TokenURL
+	}
+
+	ro = newRetryOptions()
+	if opt.HasRetryMaxAttempts {
+		ro.maxAttempts = opt.RetryMaxAttempts
+	}
+	if opt.HasRetryBaseDelay {
+		ro.baseDelay = opt.RetryBaseDelay
+	}
+	if opt.HasRetryMaxDelay {
+		ro.maxDelay = opt.RetryMaxDelay
+	}
+	if opt.HasRetryJitter {
+		ro.jitter = opt.RetryJitter
+	}
+	if opt.HasRetryHook {
+		ro.hook = opt.RetryHook
+	}
+	err = ro.validate()
 	if err != nil {
-		return nil, err
+		return ao, ro, nil, err
+	}
+
+	var metadataRate, mediaRate float64
+	if opt.HasMetadataRateLimit {
+		metadataRate = opt.MetadataRateLimit
+	}
+	if opt.HasMediaRateLimit {
+		mediaRate = opt.MediaRateLimit
+	}
+	if metadataRate < 0 {
+		return ao, ro, nil, services.PairUnsupportedError{Pair: WithMetadataRateLimit(metadataRate)}
+	}
+	if mediaRate < 0 {
+		return ao, ro, nil, services.PairUnsupportedError{Pair: WithMediaRateLimit(mediaRate)}
+	}
+	limiter = getRateLimiter(getRateLimiterKey(opt.Credential, ao), metadataRate, mediaRate)
+	return ao, ro, limiter, nil
+}
+
+// newDriveService will create an authorized http client and drive service from the credential.
+func newDriveService(cred string, opts *httpclient.Options, ao authOptions, ro retryOptions, limiter *rateLimiter) (hc *http.Client, srv *drive.Service, err error) {
+	// Tokens should be refreshed with the http client options too.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpclient.New(opts))
+
+	// Google drive only support authorized by Oauth2
+	// Ref:https://developers.google.com/drive/api/v3/about-auth
+	hc = httpclient.New(opts)
+
+	ts, err := newTokenSource(ctx, cred, ao)
+	if err != nil {
+		return nil, nil, err
 	}
 	ot := &oauth2.Transport{
-		Source: creds.TokenSource,
+		Source: ts,
 		Base:   hc.Transport,
 	}
-	hc.Transport = ot
+	// Retry outside of oauth2, so that every attempt will use a valid token, and every
+	// attempt will be limited by the rate limiter.
+	hc.Transport = newRetryTransport(newRateLimitTransport(ot, limiter), ro)
 
-	store.service, err = drive.NewService(ctx, option.WithHTTPClient(hc))
+	srv, err = drive.NewService(ctx, option.WithHTTPClient(hc))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	return store, nil
+	return hc, srv, nil
 }
 
 func formatError(err error) error {
-	if _, ok := err.(services.InternalError); ok {
+	var ie services.InternalError
+	if errors.As(err, &ie) {
+		return err
+	}
+	// Keep the context errors as is, so that callers could tell their operations are canceled.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
 
-	e, ok := err.(*googleapi.Error)
-	if !ok {
+	var e *googleapi.Error
+	if !errors.As(err, &e) {
 		return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
 	}
 
-	// FIXME: find better way to deal with errors.
-	//Ref: https://developers.google.com/drive/api/v3/handle-errors
-	switch e.Code {
-	case 400:
+	// The reason tells us more than the status code, e.g. gdrive returns 403 for both
+	// rate limit and permission denied.
+	// Ref: https://developers.google.com/drive/api/v3/handle-errors
+	for _, v := range e.Errors {
+		switch v.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "sharingRateLimitExceeded", "dailyLimitExceeded":
+			return fmt.Errorf("%w: %v", services.ErrRequestThrottled, err)
+		case "insufficientFilePermissions", "insufficientPermissions", "appNotAuthorizedToFile",
+			"domainPolicy", "forbidden":
+			return fmt.Errorf("%w: %v", services.ErrPermissionDenied, err)
+		case "fileNotFound", "notFound":
+			return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
+		case "authError", "invalidCredentials":
+			return fmt.Errorf("%w: %v", credential.ErrInvalidValue, err)
+		case "backendError", "internalError":
+			return fmt.Errorf("%w: %v", services.ErrServiceInternal, err)
+		case "storageQuotaExceeded", "quotaExceeded":
+			return fmt.Errorf("%w: %v", ErrStorageQuotaExceeded, err)
+		case "teamDriveFileLimitExceeded", "numChildrenInNonRootLimitExceeded":
+			return fmt.Errorf("%w: %v", ErrFileLimitExceeded, err)
+		case "cannotDeleteRootFolder":
+			return fmt.Errorf("%w: %v", ErrRootFolderUndeletable, err)
+		case "fileNotDownloadable":
+			return fmt.Errorf("%w: %v", ErrFileNotDownloadable, err)
+		}
+	}
+
+	switch {
+	case e.Code == http.StatusBadRequest:
 		return fmt.Errorf("%w: %v", services.ErrCapabilityInsufficient, err)
-	case 401:
+	case e.Code == http.StatusUnauthorized:
 		return fmt.Errorf("%w: %v", credential.ErrInvalidValue, err)
-	case 403:
-		return fmt.Errorf("%w: %v", services.ErrRequestThrottled, err)
-	case 404:
+	case e.Code == http.StatusForbidden:
+		return fmt.Errorf("%w: %v", services.ErrPermissionDenied, err)
+	case e.Code == http.StatusNotFound:
 		return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
-	case 500:
+	case e.Code == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %v", services.ErrRequestThrottled, err)
+	case e.Code >= http.StatusInternalServerError:
 		return fmt.Errorf("%w: %v", services.ErrServiceInternal, err)
 	default:
 		return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
 	}
 }
 
+// hasErrorReason checks whether the error is returned by gdrive with the reason.
+func hasErrorReason(err error, reason string) bool {
+	var e *googleapi.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, v := range e.Errors {
+		if v.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFound checks whether the error means the file doesn't exist.
+func isNotFound(err error) bool {
+	var e *googleapi.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code == http.StatusNotFound || hasErrorReason(err, "fileNotFound")
+}
+
+// isRateLimited checks whether the request is rejected by the rate limit, which is worth retrying
+// later. gdrive returns 403 instead of 429 for it sometimes.
+func isRateLimited(err error) bool {
+	return hasErrorReason(err, "rateLimitExceeded") || hasErrorReason(err, "userRateLimitExceeded")
+}
+
+// isFileNotDownloadable checks whether the error is caused by downloading a Google Workspace document.
+func isFileNotDownloadable(err error) bool {
+	return hasErrorReason(err, "fileNotDownloadable")
+}
+
+func (s *Service) formatError(op string, err error, name string) error {
+	if err == nil {
+		return nil
+	}
+
+	return services.ServiceError{
+		Op:       op,
+		Err:      formatError(err),
+		Servicer: s,
+		Name:     name,
+	}
+}
+
+// newStorage will create a storage rooted at the shared drive with the service's client.
+func (s *Service) newStorage(driveId, name string) (store *Storage, err error) {
+	opt, err := parsePairStorageNew([]types.Pair{
+		ps.WithName(name),
+		WithDriveID(driveId),
+		ps.WithCredential(s.credential),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(opt, s.client, s.service)
+}
+
 func (s *Storage) formatError(op string, err error, path ...string) error {
 	if err == nil {
 		return nil
@@ -177,6 +475,94 @@ func (s *Storage) newObject(done bool) *types.Object {
 	return types.NewObject(s, done)
 }
 
+// formatFileObject will convert a drive file into an object, path should be the abs path of the file.
+func (s *Storage) formatFileObject(f *drive.File, path string) (o *types.Object, err error) {
+	o = s.newObject(true)
+	o.ID = path
+	o.Path = s.getRelPath(path)
+	o.SetContentLength(f.Size)
+
+	if f.MimeType == directoryMimeType {
+		o.Mode |= types.ModeDir
+	} else {
+		o.Mode |= types.ModeRead
+	}
+
+	if f.MimeType != "" {
+		o.SetContentType(f.MimeType)
+	}
+	// gdrive returns md5 in hex, but content md5 should be base64 encoded.
+	if f.Md5Checksum != "" {
+		sum, err := hex.DecodeString(f.Md5Checksum)
+		if err != nil {
+			return nil, err
+		}
+		o.SetContentMd5(base64.StdEncoding.EncodeToString(sum))
+	}
+	if f.ModifiedTime != "" {
+		t, err := time.Parse(time.RFC3339, f.ModifiedTime)
+		if err != nil {
+			return nil, err
+		}
+		o.SetLastModified(t)
+	}
+
+	sm := ObjectSystemMetadata{
+		FileID:         f.Id,
+		HeadRevisionID: f.HeadRevisionId,
+		Md5Checksum:    f.Md5Checksum,
+		MimeType:       f.MimeType,
+		Trashed:        f.Trashed,
+		Version:        f.Version,
+		WebViewLink:    f.WebViewLink,
+		// Folders share the same prefix, but they are not documents.
+		WorkspaceDocument: f.MimeType != directoryMimeType && strings.HasPrefix(f.MimeType, workspaceMimeTypePrefix),
+	}
+	for _, v := range f.Owners {
+		sm.Owners = append(sm.Owners, v.EmailAddress)
+	}
+	setObjectSystemMetadata(o, sm)
+
+	return o, nil
+}
+
+// getRootId returns the fileId of the root folder, which is the id of the shared drive if specified.
+func (s *Storage) getRootId() string {
+	if s.driveId != "" {
+		return s.driveId
+	}
+	return "root"
+}
+
+// getWorkDirId returns the fileId which paths are resolved from, it's the work dir if
+// work_dir_id is specified, otherwise the root folder.
+func (s *Storage) getWorkDirId() string {
+	if s.workDirId != "" {
+		return s.workDirId
+	}
+	return s.getRootId()
+}
+
+// getWalkPath returns the path to walk from getWorkDirId by an abs path.
+func (s *Storage) getWalkPath(path string) string {
+	if s.workDirId == "" {
+		return path
+	}
+	if path == strings.Trim(s.workDir, "/") {
+		return ""
+	}
+	return strings.TrimPrefix(s.getRelPath(path), "/")
+}
+
+// newFilesListCall will create a files list call which could see contents in shared drives.
+func (s *Storage) newFilesListCall() *drive.FilesListCall {
+	call := s.service.Files.List().SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if s.driveId != "" {
+		call = call.Corpora("drive").DriveId(s.driveId)
+	}
+	return call
+}
+
 // getAbsPath will calculate object storage's abs path
 func (s *Storage) getAbsPath(path string) string {
 	if strings.HasPrefix(path, s.workDir) {
@@ -191,12 +577,28 @@ func (s *Storage) getAbsPath(path string) string {
 	}
 }
 
+// isSubPath checks whether the abs path is under the parent abs path.
+func isSubPath(parent, path string) bool {
+	parent = strings.Trim(parent, "/")
+	path = strings.Trim(path, "/")
+	if parent == "" {
+		return path != ""
+	}
+	return strings.HasPrefix(path, parent+"/")
+}
+
 // getRelPath will get object storage's rel path.
 func (s *Storage) getRelPath(path string) string {
 	prefix := strings.TrimPrefix(s.workDir, "/") + "/"
 	return strings.TrimPrefix(path, prefix)
 }
 
+// getAppendPath will get the local spool path of an append object by it's abs path.
+func (s *Storage) getAppendPath(path string) string {
+	sum := sha256.Sum256([]byte(s.name + ":" + path))
+	return filepath.Join(s.tempDir, appendRootName, hex.EncodeToString(sum[:]))
+}
+
 // getFileName will get a file's name without path
 func (s *Storage) getFileName(path string) string {
 	if strings.Contains(path, "/") {
@@ -207,29 +609,87 @@ func (s *Storage) getFileName(path string) string {
 	}
 }
 
-func initCache(nc int64, mc int64, bi int64) (cache *Cache, err error) {
-	config := &Config{
-		NumCounters: nc,
-		MaxCost:     mc,
-		BufferItems: bi,
+// getCacheKey returns the key of an abs path in cache. Paths are resolved from different folders
+// with different work_dir_id or drive_id, so they are prefixed with the folder's fileId, which
+// makes it possible to share a Cache between storages.
+func (s *Storage) getCacheKey(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return s.getWorkDirId()
 	}
+	return s.getWorkDirId() + "/" + path
+}
 
-	cache, err = NewCache(config)
+func (s *Storage) setCache(path string, fileId string) {
+	s.cache.Set(s.getCacheKey(path), fileId)
+}
 
-	if err != nil {
-		return nil, err
+func (s *Storage) getCache(path string) (string, bool) {
+	return s.cache.Get(s.getCacheKey(path))
+}
+
+func (s *Storage) delCache(path string) {
+	s.cache.Delete(s.getCacheKey(path))
+}
+
+// delCacheTree will delete the cache of path and all of it's descendants, it should be used
+// while the path could be a directory.
+func (s *Storage) delCacheTree(path string) {
+	s.cache.DeleteTree(s.getCacheKey(path))
+}
+
+// contextReader will abort reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func newContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
-	return cache, nil
+	return r.r.Read(p)
 }
 
-func (s *Storage) setCache(path string, fileId string) {
-	s.cache.SetWithTTL(path, fileId, cost, expireTime)
+// keyedMutex provides a mutex for every key, the mutex will be released once nobody holds it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedMutexEntry
 }
 
-func (s *Storage) getCache(path string) (string, bool) {
-	id, found := s.cache.Get(path)
-	if found {
-		return id.(string), true
+type keyedMutexEntry struct {
+	sync.Mutex
+	// ref is the count of callers holding or waiting for the mutex.
+	ref int
+}
+
+// lock will lock the key and return the function to unlock it.
+func (m *keyedMutex) lock(key string) (unlock func()) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedMutexEntry)
+	}
+	e, ok := m.locks[key]
+	if !ok {
+		e = &keyedMutexEntry{}
+		m.locks[key] = e
+	}
+	e.ref++
+	m.mu.Unlock()
+
+	e.Lock()
+	return func() {
+		e.Unlock()
+
+		m.mu.Lock()
+		e.ref--
+		if e.ref == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
 	}
-	return "", false
 }
@@ -0,0 +1,120 @@
+package gdrive
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/pkg/credential"
+	"github.com/beyondstorage/go-storage/v4/services"
+	"github.com/beyondstorage/go-storage/v4/types"
+)
+
+func newGoogleAPIError(code int, reason string) error {
+	e := &googleapi.Error{Code: code, Message: reason}
+	if reason != "" {
+		e.Errors = []googleapi.ErrorItem{{Reason: reason}}
+	}
+	return e
+}
+
+func TestFormatError(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		expect error
+	}{
+		{"rate limit", newGoogleAPIError(403, "rateLimitExceeded"), services.ErrRequestThrottled},
+		{"user rate limit", newGoogleAPIError(403, "userRateLimitExceeded"), services.ErrRequestThrottled},
+		{"too many requests", newGoogleAPIError(429, ""), services.ErrRequestThrottled},
+		{"permission", newGoogleAPIError(403, "insufficientFilePermissions"), services.ErrPermissionDenied},
+		{"forbidden without reason", newGoogleAPIError(403, ""), services.ErrPermissionDenied},
+		{"file not found", newGoogleAPIError(404, "notFound"), services.ErrObjectNotExist},
+		{"not found without reason", newGoogleAPIError(404, ""), services.ErrObjectNotExist},
+		{"auth", newGoogleAPIError(401, "authError"), credential.ErrInvalidValue},
+		{"backend", newGoogleAPIError(503, "backendError"), services.ErrServiceInternal},
+		{"bad gateway", newGoogleAPIError(502, ""), services.ErrServiceInternal},
+		{"bad request", newGoogleAPIError(400, "badRequest"), services.ErrCapabilityInsufficient},
+		{"storage quota", newGoogleAPIError(403, "storageQuotaExceeded"), ErrStorageQuotaExceeded},
+		{"file limit", newGoogleAPIError(403, "teamDriveFileLimitExceeded"), ErrFileLimitExceeded},
+		{"root folder", newGoogleAPIError(403, "cannotDeleteRootFolder"), ErrRootFolderUndeletable},
+		{"not downloadable", newGoogleAPIError(403, "fileNotDownloadable"), ErrFileNotDownloadable},
+		{"wrapped", fmt.Errorf("upload: %w", newGoogleAPIError(403, "rateLimitExceeded")), services.ErrRequestThrottled},
+		{"not googleapi", errors.New("connection reset"), services.ErrUnexpected},
+		{"internal", ContentMd5MismatchError{}, ErrContentMd5Mismatch},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := formatError(tt.err); !errors.Is(err, tt.expect) {
+				t.Errorf("formatError(%v) = %v, expect %v", tt.err, err, tt.expect)
+			}
+		})
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !isNotFound(fmt.Errorf("delete: %w", newGoogleAPIError(http.StatusNotFound, "notFound"))) {
+		t.Errorf("404 should be not found")
+	}
+	if isNotFound(newGoogleAPIError(http.StatusForbidden, "insufficientFilePermissions")) {
+		t.Errorf("403 should not be not found")
+	}
+	// The message could contain 404 while the error is not.
+	if isNotFound(errors.New("file 404.txt is broken")) {
+		t.Errorf("error mentioning 404 should not be not found")
+	}
+}
+
+func TestIsSubPath(t *testing.T) {
+	cases := []struct {
+		parent string
+		path   string
+		expect bool
+	}{
+		{"work/a", "work/a/b", true},
+		{"work/a", "work/a/b/c", true},
+		{"/a", "/a/b", true},
+		{"work/a", "work/a", false},
+		{"work/a", "work/ab", false},
+		{"work/a/b", "work/a", false},
+		{"", "a", true},
+		{"", "", false},
+	}
+
+	for _, tt := range cases {
+		if got := isSubPath(tt.parent, tt.path); got != tt.expect {
+			t.Errorf("isSubPath(%q, %q) = %v, expect %v", tt.parent, tt.path, got, tt.expect)
+		}
+	}
+}
+
+func TestNewClientPairsInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		pair types.Pair
+	}{
+		{"retry max attempts", WithRetryMaxAttempts(0)},
+		{"retry jitter", WithRetryJitter(2)},
+		{"metadata rate limit", WithMetadataRateLimit(-1)},
+		{"media rate limit", WithMediaRateLimit(-1)},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := []types.Pair{ps.WithName("test"), ps.WithCredential("hmac:id:secret"), tt.pair}
+
+			var e services.PairUnsupportedError
+			if _, err := NewServicer(pairs...); !errors.As(err, &e) {
+				t.Errorf("new servicer got %v, expect PairUnsupportedError", err)
+			}
+			if _, err := NewStorager(pairs...); !errors.As(err, &e) {
+				t.Errorf("new storager got %v, expect PairUnsupportedError", err)
+			}
+		})
+	}
+}